@@ -0,0 +1,365 @@
+package extract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/modfile"
+)
+
+// cacheVersion is part of every cache key, and must be changed whenever the
+// extracted strings change for the same input, so that old entries are not used
+const cacheVersion = "7"
+
+// maxCacheAge is how long an entry is kept in the cache after it was last used. Every change to a file
+// adds a new entry, e.g. on every save in watch mode, so old entries must be removed.
+const maxCacheAge = 30 * 24 * time.Hour
+
+// pruneInterval is how often the cache is searched for old entries to remove
+const pruneInterval = 24 * time.Hour
+
+// pruneMarker is the file in the cache folder whose modification time is when it was last pruned
+const pruneMarker = "pruned"
+
+// Cache stores the strings extracted from files on disk, keyed by a hash of the contents of the files
+// and the options used, so that files that haven't changed since the last run don't have to be parsed again.
+// A nil *Cache is valid, and doesn't cache anything.
+type Cache struct {
+	dir string
+}
+
+// DefaultCacheDir returns the folder used for the cache if no other is given, in the user's cache folder
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "makemessage"), nil
+}
+
+// NewCache returns a cache storing its entries in dir, which is created if it doesn't exist.
+// Entries that haven't been used for maxCacheAge are removed, at most once per pruneInterval.
+func NewCache(dir string) (*Cache, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cache folder '%s': %w", dir, err)
+	}
+
+	c := &Cache{dir: dir}
+	c.prune(time.Now())
+	return c, nil
+}
+
+// prune removes the entries that haven't been used for maxCacheAge, unless that was done less than
+// pruneInterval ago. The cache works without pruning, so entries that cannot be removed are left.
+func (c *Cache) prune(now time.Time) {
+	marker := filepath.Join(c.dir, pruneMarker)
+	if st, err := os.Stat(marker); err == nil && now.Sub(st.ModTime()) < pruneInterval {
+		return
+	}
+	if os.WriteFile(marker, nil, 0644) != nil || os.Chtimes(marker, now, now) != nil {
+		return
+	}
+
+	filepath.Walk(c.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() || path == marker {
+			return nil
+		}
+		if now.Sub(info.ModTime()) > maxCacheAge {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// cacheKey returns the hash of parts, as used to name cache entries
+func cacheKey(parts ...[]byte) string {
+	h := sha256.New()
+	h.Write([]byte(cacheVersion))
+	for _, p := range parts {
+		// Write the length first, so that different splits of the same bytes give different keys
+		fmt.Fprintf(h, "\x00%d\x00", len(p))
+		h.Write(p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path returns the path of the cache entry for key
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// get returns the strings stored for key. Entries that cannot be read are treated as missing.
+// The modification time of the entry is set to the current time, so that it's not pruned while in use.
+func (c *Cache) get(key string) ([]TranslationString, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var strs []TranslationString
+	if json.Unmarshal(data, &strs) != nil {
+		return nil, false
+	}
+
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return strs, true
+}
+
+// put stores strs for key. The entry is written to a temporary file first, so that
+// other processes never read a partially written entry.
+func (c *Cache) put(key string, strs []TranslationString) error {
+	data, err := json.Marshal(strs)
+	if err != nil {
+		return err
+	}
+
+	path := c.path(key)
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Extract returns the translation strings in content, read from the file at path, using e.
+// If the same content has been extracted from path by an extractor of the same type and with the
+// same settings before, the cached strings are returned instead.
+func (c *Cache) Extract(e Extractor, path string, content []byte) ([]TranslationString, error) {
+	if c == nil {
+		return e.Extract(path, content)
+	}
+
+	key := cacheKey([]byte(fmt.Sprintf("%#v", e)), []byte(path), content)
+	if strs, ok := c.get(key); ok {
+		return strs, nil
+	}
+
+	// Strings found with warnings are not cached, so that the warnings are repeated until fixed
+	strs, err := e.Extract(path, content)
+	if err != nil {
+		return strs, err
+	}
+	return strs, c.put(key, strs)
+}
+
+// packageKey returns the cache key for the go package in dir, based on the keywords and domain rules used,
+// and on everything the strings found in it depend on: the contents of all go files in the package and in
+// the packages of the same module it imports, directly or indirectly, the go.mod and go.sum files, which
+// fix the versions of all other modules, and the go version, for the standard library. Test files are
+// not parsed, and are not included. Files that are excluded by build constraints are included, so that
+// the key changes whenever any file changes.
+//
+// Packages whose dependencies are not all covered by the key are not cached, and an empty key is returned.
+// This is the case when a go.work file is used, or when go.mod replaces a module with a local folder,
+// as the packages in those folders may change without any change to go.mod or go.sum.
+func packageKey(basePath string, dir string, keywords []Keyword, rules []DomainRule) (string, error) {
+	config, err := json.Marshal(struct {
+		Keywords []Keyword
+		Rules    []DomainRule
+	}{keywords, rules})
+	if err != nil {
+		return "", err
+	}
+
+	parts := [][]byte{[]byte(basePath), []byte(dir), config, []byte(runtime.Version())}
+
+	root, modulePath, err := findModule(dir)
+	if err != nil {
+		return "", err
+	}
+	if root != "" {
+		if local, err := usesLocalModules(root); err != nil || local {
+			return "", err
+		}
+
+		for _, name := range []string{"go.mod", "go.sum"} {
+			content, err := os.ReadFile(filepath.Join(root, name))
+			if err != nil && !os.IsNotExist(err) {
+				return "", err
+			}
+			parts = append(parts, []byte(name), content)
+		}
+	}
+
+	dirs := []string{dir}
+	seen := map[string]bool{dir: true}
+	for i := 0; i < len(dirs); i++ {
+		files, imports, err := readPackage(dirs[i])
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, []byte(dirs[i]))
+		parts = append(parts, files...)
+
+		for _, imp := range imports {
+			if modulePath == "" || (imp != modulePath && !strings.HasPrefix(imp, modulePath+"/")) {
+				continue
+			}
+
+			impDir := filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(imp, modulePath)))
+			if !seen[impDir] {
+				seen[impDir] = true
+				dirs = append(dirs, impDir)
+			}
+		}
+	}
+	return cacheKey(parts...), nil
+}
+
+// readPackage returns the names and contents of all non-test go files in dir, in sorted order,
+// and the sorted import paths imported by them
+func readPackage(dir string) ([][]byte, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && filepath.Ext(name) == ".go" && !strings.HasSuffix(name, "_test.go") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var files [][]byte
+	importSet := map[string]bool{}
+	fset := token.NewFileSet()
+	for _, name := range names {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, []byte(name), content)
+
+		// Files that cannot be parsed are still part of the key, and their errors are left to the loader
+		f, err := parser.ParseFile(fset, name, content, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, spec := range f.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+				importSet[path] = true
+			}
+		}
+	}
+
+	imports := make([]string, 0, len(importSet))
+	for path := range importSet {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	return files, imports, nil
+}
+
+// findModule returns the folder containing the go.mod file of the module that dir is in, and the
+// module path declared in it. If dir isn't in a module, an empty folder and path are returned.
+func findModule(dir string) (string, string, error) {
+	for d := dir; ; d = filepath.Dir(d) {
+		content, err := os.ReadFile(filepath.Join(d, "go.mod"))
+		if err == nil {
+			return d, modulePath(content), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", err
+		}
+
+		if filepath.Dir(d) == d {
+			return "", "", nil
+		}
+	}
+}
+
+// usesLocalModules checks if the module in root may use packages from other modules in local folders,
+// i.e. if go.mod replaces a module with a folder, or if a go.work file applies to it
+func usesLocalModules(root string) (bool, error) {
+	content, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return false, err
+	}
+
+	// Files that cannot be parsed are left to the loader, which reports the errors
+	if f, err := modfile.Parse("go.mod", content, nil); err == nil {
+		for _, r := range f.Replace {
+			if modfile.IsDirectoryPath(r.New.Path) {
+				return true, nil
+			}
+		}
+	}
+
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return false, nil
+	case "":
+		for d := root; ; d = filepath.Dir(d) {
+			if _, err := os.Stat(filepath.Join(d, "go.work")); err == nil {
+				return true, nil
+			}
+			if filepath.Dir(d) == d {
+				return false, nil
+			}
+		}
+	default:
+		return true, nil
+	}
+}
+
+// modulePath returns the module path declared in the contents of a go.mod file
+func modulePath(gomod []byte) string {
+	for _, line := range strings.Split(string(gomod), "\n") {
+		line = strings.TrimSpace(line)
+		if rest, found := strings.CutPrefix(line, "module"); found && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			rest, _, _ = strings.Cut(rest, "//")
+			rest = strings.TrimSpace(rest)
+			if path, err := strconv.Unquote(rest); err == nil {
+				return path
+			}
+			return rest
+		}
+	}
+	return ""
+}
+
+// patternDir returns the folder of pattern, if it names a single package by its folder, e.g. "." or "./sub"
+func patternDir(basePath string, pattern string) (string, bool) {
+	if strings.Contains(pattern, "...") {
+		return "", false
+	}
+
+	if filepath.IsAbs(pattern) {
+		return filepath.Clean(pattern), true
+	}
+
+	if pattern == "." || pattern == ".." || strings.HasPrefix(pattern, "./") || strings.HasPrefix(pattern, "../") {
+		return filepath.Join(basePath, pattern), true
+	}
+	return "", false
+}
@@ -0,0 +1,167 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingExtractor returns a single string for every file, and counts the number of calls
+type countingExtractor struct {
+	calls *int
+}
+
+func (e countingExtractor) Extensions() []string { return []string{".txt"} }
+
+func (e countingExtractor) Extract(path string, content []byte) ([]TranslationString, error) {
+	*e.calls++
+	return []TranslationString{{Path: path, Line: 1, Column: 1, Singular: string(content)}}, nil
+}
+
+func TestCacheExtract(t *testing.T) {
+	cache, err := NewCache(t.TempDir())
+	require.Nil(t, err)
+
+	calls := 0
+	e := countingExtractor{calls: &calls}
+
+	for i := 0; i < 2; i++ {
+		strs, err := cache.Extract(e, "a.txt", []byte("Hello"))
+		require.Nil(t, err)
+		require.Equal(t, []TranslationString{{Path: "a.txt", Line: 1, Column: 1, Singular: "Hello"}}, strs)
+	}
+	require.Equal(t, 1, calls)
+
+	_, err = cache.Extract(e, "a.txt", []byte("Hello again"))
+	require.Nil(t, err)
+	_, err = cache.Extract(e, "b.txt", []byte("Hello"))
+	require.Nil(t, err)
+	require.Equal(t, 3, calls)
+
+	// A nil cache doesn't cache anything
+	var noCache *Cache
+	_, err = noCache.Extract(e, "a.txt", []byte("Hello"))
+	require.Nil(t, err)
+	require.Equal(t, 4, calls)
+}
+
+func TestCachePrune(t *testing.T) {
+	cache, err := NewCache(t.TempDir())
+	require.Nil(t, err)
+
+	now := time.Now()
+	old := now.Add(-maxCacheAge - time.Hour)
+	keys := map[string]string{"unused": cacheKey([]byte("unused")), "used": cacheKey([]byte("used")), "new": cacheKey([]byte("new"))}
+	for _, key := range keys {
+		require.Nil(t, cache.put(key, nil))
+	}
+	require.Nil(t, os.Chtimes(cache.path(keys["unused"]), old, old))
+	require.Nil(t, os.Chtimes(cache.path(keys["used"]), old, old))
+
+	// Using an entry keeps it in the cache
+	_, ok := cache.get(keys["used"])
+	require.True(t, ok)
+
+	// The cache was pruned when it was created, so it's not pruned again until pruneInterval has passed
+	cache.prune(now)
+	_, ok = cache.get(keys["unused"])
+	require.True(t, ok)
+	require.Nil(t, os.Chtimes(cache.path(keys["unused"]), old, old))
+
+	cache.prune(now.Add(pruneInterval + time.Hour))
+	_, ok = cache.get(keys["unused"])
+	require.False(t, ok)
+	_, ok = cache.get(keys["used"])
+	require.True(t, ok)
+	_, ok = cache.get(keys["new"])
+	require.True(t, ok)
+}
+
+func TestParseGoCache(t *testing.T) {
+	cache, err := NewCache(t.TempDir())
+	require.Nil(t, err)
+
+	cwd, _ := os.Getwd()
+	basePath := filepath.Join(cwd, "testdata")
+
+	uncached := NewMsgHolder()
+	require.Nil(t, ParseGo(basePath, []string{"./comments"}, uncached, GoOptions{}))
+
+	for i := 0; i < 2; i++ {
+		msgHolder := NewMsgHolder()
+		require.Nil(t, ParseGo(basePath, []string{"./comments"}, msgHolder, GoOptions{Cache: cache}))
+		require.Equal(t, uncached.Strings("default"), msgHolder.Strings("default"))
+	}
+
+	// The cached strings are used as long as the files of the package are unchanged
+	key, err := packageKey(basePath, filepath.Join(basePath, "comments"), DefaultKeywords, nil)
+	require.Nil(t, err)
+	require.Nil(t, cache.put(key, []TranslationString{{Path: "comments/comments.go", Singular: "Cached"}}))
+
+	msgHolder := NewMsgHolder()
+	require.Nil(t, ParseGo(basePath, []string{"./comments"}, msgHolder, GoOptions{Cache: cache}))
+	require.Len(t, msgHolder.Strings("default"), 1)
+	require.Equal(t, "Cached", msgHolder.Strings("default")[0].Singular)
+
+	// With a different configuration, the package is loaded again
+	msgHolder = NewMsgHolder()
+	require.Nil(t, ParseGo(basePath, []string{"./comments"}, msgHolder, GoOptions{Cache: cache, Keywords: DefaultKeywords[:1]}))
+	require.NotEqual(t, "Cached", msgHolder.Strings("default")[0].Singular)
+}
+
+func TestPackageKeyDependencies(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) {
+		require.Nil(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	t.Setenv("GOWORK", "")
+	key := func() string {
+		key, err := packageKey(dir, filepath.Join(dir, "app"), DefaultKeywords, nil)
+		require.Nil(t, err)
+		return key
+	}
+
+	write("go.mod", "module example.com/m // comment\n\ngo 1.21\n")
+	write("app/app.go", "package app\n\nimport (\n\t\"fmt\"\n\t\"example.com/m/i18n\"\n)\n\nvar _ = fmt.Sprint(i18n.T(\"Hello\"))\n")
+	write("i18n/i18n.go", "package i18n\n\nimport \"example.com/m/i18n/internal\"\n\nfunc T(s string) string { return internal.T(s) }\n")
+	write("i18n/internal/internal.go", "package internal\n\nfunc T(s string) string { return s }\n")
+	write("other/other.go", "package other\n")
+
+	first := key()
+	require.Equal(t, first, key())
+
+	// Packages that aren't imported don't change the key
+	write("other/other.go", "package other\n\nfunc T(s string) string { return s }\n")
+	require.Equal(t, first, key())
+
+	// Packages imported directly or indirectly, and the module files, do
+	keys := map[string]bool{first: true}
+	for _, change := range []struct{ name, content string }{
+		{"i18n/i18n.go", "package i18n\n\nimport \"example.com/m/i18n/internal\"\n\nfunc T(s string, args ...any) string { return internal.T(s) }\n"},
+		{"i18n/internal/internal.go", "package internal\n\nfunc T(s string) string { return s + \"\" }\n"},
+		{"go.sum", "example.com/other v1.0.0 h1:abc=\n"},
+		{"go.mod", "module example.com/m\n\ngo 1.22\n"},
+	} {
+		write(change.name, change.content)
+		k := key()
+		require.False(t, keys[k], "key unchanged after changing %s", change.name)
+		keys[k] = true
+	}
+
+	// Packages that may depend on modules in other folders are not cached
+	write("go.mod", "module example.com/m\n\ngo 1.22\n\nreplace example.com/other => ../other\n")
+	require.Equal(t, "", key())
+
+	write("go.mod", "module example.com/m\n\ngo 1.22\n\nreplace example.com/other => example.com/fork v1.0.0\n")
+	require.NotEqual(t, "", key())
+
+	write("go.work", "go 1.22\n\nuse .\n")
+	require.Equal(t, "", key())
+
+	t.Setenv("GOWORK", "off")
+	require.NotEqual(t, "", key())
+}
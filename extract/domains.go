@@ -0,0 +1,114 @@
+package extract
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DomainRule puts the strings found in the packages or templates matching a pattern in a domain,
+// unless the domain is given in the translation call
+type DomainRule struct {
+	Pattern string
+	Domain  string
+
+	re *regexp.Regexp
+}
+
+// DomainRules selects the domain of strings by where they were found. The first matching rule is used.
+type DomainRules struct {
+	// Packages match import paths. "..." matches any string, and a pattern
+	// ending with "/..." also matches the package itself, as with the go command.
+	Packages []DomainRule
+
+	// Templates match the paths of templates, as written in references. "*" matches any
+	// part of a file or folder name, "?" matches a single character, and "**" matches
+	// any number of folders, e.g. "templates/admin/**" or "**/*.admin.html".
+	Templates []DomainRule
+}
+
+// packagePatternRegexp converts an import path pattern to a regular expression
+func packagePatternRegexp(pattern string) (*regexp.Regexp, error) {
+	var expr string
+	if prefix, found := strings.CutSuffix(pattern, "/..."); found {
+		pattern, expr = prefix, "(/.*)?"
+	}
+
+	parts := strings.Split(pattern, "...")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return regexp.Compile("^" + strings.Join(parts, ".*") + expr + "$")
+}
+
+// globRegexp converts a path glob to a regular expression
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// parseDomainRule parses a rule given as "pattern=domain", using compile to convert the pattern
+func parseDomainRule(s string, compile func(string) (*regexp.Regexp, error)) (DomainRule, error) {
+	pattern, domain, found := strings.Cut(s, "=")
+	if !found || pattern == "" || domain == "" {
+		return DomainRule{}, fmt.Errorf("invalid domain rule '%s', expected 'pattern=domain'", s)
+	}
+
+	re, err := compile(pattern)
+	if err != nil {
+		return DomainRule{}, fmt.Errorf("invalid pattern in domain rule '%s': %w", s, err)
+	}
+	return DomainRule{Pattern: pattern, Domain: domain, re: re}, nil
+}
+
+// ParsePackageDomainRule parses a rule for packages, given as "importpath=domain",
+// e.g. "github.com/org/app/internal/billing/...=billing"
+func ParsePackageDomainRule(s string) (DomainRule, error) {
+	return parseDomainRule(s, packagePatternRegexp)
+}
+
+// ParseTemplateDomainRule parses a rule for templates, given as "glob=domain", e.g. "templates/admin/**=admin"
+func ParseTemplateDomainRule(s string) (DomainRule, error) {
+	return parseDomainRule(s, globRegexp)
+}
+
+// matchDomain returns the domain of the first rule in rules matching s, or an empty string if none does
+func matchDomain(rules []DomainRule, s string) string {
+	for _, r := range rules {
+		if r.re != nil && r.re.MatchString(s) {
+			return r.Domain
+		}
+	}
+	return ""
+}
+
+// PackageDomain returns the domain for strings found in the package with the given import path,
+// or an empty string if no rule matches
+func (r DomainRules) PackageDomain(importPath string) string {
+	return matchDomain(r.Packages, importPath)
+}
+
+// TemplateDomain returns the domain for strings found in the template at path,
+// or an empty string if no rule matches
+func (r DomainRules) TemplateDomain(path string) string {
+	return matchDomain(r.Templates, filepath.ToSlash(path))
+}
@@ -0,0 +1,69 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomainRules(t *testing.T) {
+	var rules DomainRules
+	for _, r := range []string{"github.com/org/app/internal/billing/...=billing", "github.com/org/app/admin=admin", "github.com/org/.../legacy=legacy"} {
+		rule, err := ParsePackageDomainRule(r)
+		require.Nil(t, err)
+		rules.Packages = append(rules.Packages, rule)
+	}
+	for _, r := range []string{"templates/admin/**=admin", "**/*.billing.html=billing", "static/?.js=short"} {
+		rule, err := ParseTemplateDomainRule(r)
+		require.Nil(t, err)
+		rules.Templates = append(rules.Templates, rule)
+	}
+
+	packages := map[string]string{
+		"github.com/org/app/internal/billing":         "billing",
+		"github.com/org/app/internal/billing/invoice": "billing",
+		"github.com/org/app/internal/billingx":        "",
+		"github.com/org/app/admin":                    "admin",
+		"github.com/org/app/admin/users":              "",
+		"github.com/org/tools/legacy":                 "legacy",
+		"github.com/org/app":                          "",
+	}
+	for path, domain := range packages {
+		require.Equal(t, domain, rules.PackageDomain(path), path)
+	}
+
+	templates := map[string]string{
+		"templates/admin/index.html":       "admin",
+		"templates/admin/users/list.html":  "admin",
+		"templates/index.billing.html":     "billing",
+		"invoice.billing.html":             "billing",
+		"templates/administration/a.html":  "",
+		"static/a.js":                      "short",
+		"static/ab.js":                     "",
+		"templates/admin.html":             "",
+		"templates/shop/cart.billing.html": "billing",
+	}
+	for path, domain := range templates {
+		require.Equal(t, domain, rules.TemplateDomain(path), path)
+	}
+
+	_, err := ParsePackageDomainRule("github.com/org/app")
+	require.NotNil(t, err)
+	_, err = ParseTemplateDomainRule("=admin")
+	require.NotNil(t, err)
+}
+
+func TestParseGoDomainRules(t *testing.T) {
+	rule, err := ParsePackageDomainRule("github.com/yzzyx/makemessage/testdata/...=testdata")
+	require.Nil(t, err)
+
+	cwd, _ := os.Getwd()
+	basePath := filepath.Join(cwd, "testdata")
+
+	msgHolder := NewMsgHolder()
+	err = ParseGo(basePath, []string{"./comments"}, msgHolder, GoOptions{Domains: DomainRules{Packages: []DomainRule{rule}}})
+	require.Nil(t, err)
+	require.Equal(t, []string{"testdata"}, msgHolder.Domains())
+}
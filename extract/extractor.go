@@ -0,0 +1,110 @@
+// Package extract finds translation strings in go packages, django and go templates, and
+// javascript and typescript code, and collects them by domain into PO templates.
+package extract
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// An Extractor extracts translation strings from a single type of file
+type Extractor interface {
+	// Extensions returns the file extensions handled by the extractor by default, e.g. ".html"
+	Extensions() []string
+
+	// Extract returns all translation strings in content, read from the file at path.
+	// If parts of the file are skipped, the strings found in the rest are returned with Warnings.
+	Extract(path string, content []byte) ([]TranslationString, error)
+}
+
+// Warnings is returned by Extract together with the strings found, if parts of the file could not be
+// parsed, e.g. a script block in a template. Each warning is a message starting with the file and line.
+type Warnings []string
+
+func (w Warnings) Error() string {
+	return strings.Join(w, "\n")
+}
+
+// DefaultExtractors returns the built-in extractors, by name. A new map is returned on each call,
+// so that the caller can replace or add extractors, e.g. a go template extractor with other keywords.
+func DefaultExtractors() map[string]Extractor {
+	return map[string]Extractor{
+		"django":     DjangoExtractor{},
+		"javascript": JavaScriptExtractor{},
+		"gotemplate": GoTemplateExtractor{},
+	}
+}
+
+// extractorNames returns the names of extractors, in sorted order
+func extractorNames(extractors map[string]Extractor) []string {
+	names := make([]string, 0, len(extractors))
+	for name := range extractors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExtractorMapping maps file extensions to the extractors given by name.
+//
+// All extractors are mapped to their default extensions, unless the extensions
+// of an extractor are given in defaults. The mapping in overrides is then applied, with
+// each entry given as ".ext=name".
+func ExtractorMapping(extractors map[string]Extractor, defaults map[string][]string, overrides []string) (map[string]Extractor, error) {
+	mapping := map[string]Extractor{}
+	for _, name := range extractorNames(extractors) {
+		extensions, ok := defaults[name]
+		if !ok {
+			extensions = extractors[name].Extensions()
+		}
+
+		for _, ext := range extensions {
+			mapping[ext] = extractors[name]
+		}
+	}
+
+	for _, o := range overrides {
+		ext, name, found := strings.Cut(o, "=")
+		if !found || !strings.HasPrefix(ext, ".") {
+			return nil, fmt.Errorf("invalid extractor mapping '%s', expected '.ext=name'", o)
+		}
+
+		e, ok := extractors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown extractor '%s', expected one of %s", name, strings.Join(extractorNames(extractors), ", "))
+		}
+		mapping[ext] = e
+	}
+	return mapping, nil
+}
+
+// lineCounter finds the line and column of offsets in content, keeping track of the last offset, so
+// that only the content between two offsets has to be scanned. Finding the positions of offsets given
+// in increasing order is therefore linear in the length of content.
+type lineCounter struct {
+	content   string
+	offset    int // The offset that line and lineStart are counted up to
+	line      int // Number of newlines before offset
+	lineStart int // Offset of the first byte of the line containing offset
+}
+
+// position returns the line and column (in bytes) of offset, both starting at 1
+func (c *lineCounter) position(offset int) (int, int) {
+	for ; c.offset < offset; c.offset++ {
+		if c.content[c.offset] == '\n' {
+			c.line++
+			c.lineStart = c.offset + 1
+		}
+	}
+
+	if c.offset > offset {
+		for ; c.offset > offset; c.offset-- {
+			if c.content[c.offset-1] == '\n' {
+				c.line--
+			}
+		}
+		c.lineStart = strings.LastIndexByte(c.content[:offset], '\n') + 1
+	}
+	return c.line + 1, offset - c.lineStart + 1
+}
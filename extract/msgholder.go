@@ -0,0 +1,283 @@
+package extract
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yzzyx/makemessage/po"
+)
+
+// TranslationString is a single translatable string found in the source
+type TranslationString struct {
+	Path     string // File the string was found in
+	Line     int    // Line of the translation call or tag, starting at 1
+	Column   int    // Column of the translation call or tag in bytes, starting at 1
+	Singular string
+	Plural   string
+	Context  string
+	Domain   string
+	Comments []string // Comments for translators, written as "#. comment"
+	Flags    []string // Flags such as "go-format", written as "#, flag"
+}
+
+// Reference returns the location of s, as "file:line", or "file:line:col" if columns is set
+func (s TranslationString) Reference(columns bool) string {
+	if columns {
+		return fmt.Sprintf("%s:%d:%d", s.Path, s.Line, s.Column)
+	}
+	return fmt.Sprintf("%s:%d", s.Path, s.Line)
+}
+
+// Message is a unique string in a domain, identified by its context and msgid,
+// combining all places where it was found
+type Message struct {
+	Context  string
+	Singular string
+	Plural   string
+	Comments []string
+	Flags    []string
+
+	// Occurrences holds every place where the message was found, sorted by location
+	Occurrences []TranslationString
+}
+
+// Conflict describes a string that was found with a different plural form than the first time
+type Conflict struct {
+	First TranslationString // The occurrence whose plural form is used
+	Other TranslationString // The occurrence with a different plural form
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: msgid %q has msgid_plural %q, but %q in %s",
+		c.Other.Reference(false), c.Other.Singular, c.Other.Plural, c.First.Plural, c.First.Reference(false))
+}
+
+// TemplateOptions controls how message templates are created
+type TemplateOptions struct {
+	Columns bool // Include columns in references, as "file:line:col"
+}
+
+// MsgHolder collects translation strings, grouped by domain. It's safe for concurrent use.
+type MsgHolder struct {
+	mu      sync.Mutex
+	strings map[string][]TranslationString
+}
+
+// NewMsgHolder returns an empty MsgHolder
+func NewMsgHolder() *MsgHolder {
+	return &MsgHolder{
+		strings: map[string][]TranslationString{},
+	}
+}
+
+// Add adds s to its domain, or to the "default" domain if none is set
+func (h *MsgHolder) Add(s TranslationString) {
+	domain := s.Domain
+	if domain == "" {
+		domain = "default"
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.strings[domain] = append(h.strings[domain], s)
+}
+
+// Remove removes all strings for which match returns true, and returns the sorted names of the domains
+// they were removed from. Domains without any remaining strings are removed.
+func (h *MsgHolder) Remove(match func(s TranslationString) bool) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var domains []string
+	for domain, strs := range h.strings {
+		n := 0
+		for _, s := range strs {
+			if !match(s) {
+				strs[n] = s
+				n++
+			}
+		}
+
+		if n == len(strs) {
+			continue
+		}
+		domains = append(domains, domain)
+
+		if n == 0 {
+			delete(h.strings, domain)
+		} else {
+			h.strings[domain] = strs[:n]
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// Domains returns the names of all domains containing strings, in sorted order
+func (h *MsgHolder) Domains() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var domains []string
+	for domain := range h.strings {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// Strings returns a copy of the strings in domain, sorted by context and location.
+// The order doesn't depend on the order the strings were added in.
+func (h *MsgHolder) Strings(domain string) []TranslationString {
+	h.mu.Lock()
+	dStrs := append([]TranslationString(nil), h.strings[domain]...)
+	h.mu.Unlock()
+
+	sort.Slice(dStrs, func(i, j int) bool {
+		a, b := dStrs[i], dStrs[j]
+		switch {
+		case a.Context != b.Context:
+			return a.Context < b.Context
+		case a.Path != b.Path:
+			return a.Path < b.Path
+		case a.Line != b.Line:
+			return a.Line < b.Line
+		case a.Column != b.Column:
+			return a.Column < b.Column
+		case a.Singular != b.Singular:
+			return a.Singular < b.Singular
+		}
+		return a.Plural < b.Plural
+	})
+	return dStrs
+}
+
+// appendUnique appends the values that are not already in list
+func appendUnique(list []string, values ...string) []string {
+outer:
+	for _, v := range values {
+		for _, existing := range list {
+			if existing == v {
+				continue outer
+			}
+		}
+		list = append(list, v)
+	}
+	return list
+}
+
+// negateFlag returns the opposite of a format flag, e.g. "no-go-format" for "go-format" and the other way around
+func negateFlag(flag string) string {
+	if f, found := strings.CutPrefix(flag, "no-"); found {
+		return f
+	}
+	return "no-" + flag
+}
+
+// appendFlags appends the flags that are not already in list. If list has the opposite of a flag,
+// e.g. "no-go-format" for "go-format", the flag already in list is kept.
+func appendFlags(list []string, flags ...string) []string {
+	for _, f := range flags {
+		if strings.HasSuffix(f, "-format") && containsString(list, negateFlag(f)) {
+			continue
+		}
+		list = appendUnique(list, f)
+	}
+	return list
+}
+
+// containsString checks if list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Messages returns the unique messages in domain, sorted by context and location.
+// Strings with the same context and msgid are combined into a single message, using the
+// first plural form found. Strings with other plural forms are returned as conflicts. If the occurrences
+// disagree on a format flag, such as "go-format" and "no-go-format", the flag of the first one is used.
+func (h *MsgHolder) Messages(domain string) ([]*Message, []Conflict) {
+	type key struct{ context, singular string }
+
+	var messages []*Message
+	var conflicts []Conflict
+	index := map[key]*Message{}
+
+	for _, s := range h.Strings(domain) {
+
+		// Ignore empty strings - the empty string is reserved for translation information
+		if s.Singular == "" {
+			continue
+		}
+
+		m, ok := index[key{s.Context, s.Singular}]
+		if !ok {
+			m = &Message{Context: s.Context, Singular: s.Singular}
+			index[key{s.Context, s.Singular}] = m
+			messages = append(messages, m)
+		}
+
+		if s.Plural != "" && m.Plural == "" {
+			m.Plural = s.Plural
+		} else if s.Plural != "" && s.Plural != m.Plural {
+			for _, o := range m.Occurrences {
+				if o.Plural == m.Plural {
+					conflicts = append(conflicts, Conflict{First: o, Other: s})
+					break
+				}
+			}
+		}
+
+		m.Comments = appendUnique(m.Comments, s.Comments...)
+		m.Flags = appendFlags(m.Flags, s.Flags...)
+		m.Occurrences = append(m.Occurrences, s)
+	}
+	return messages, conflicts
+}
+
+// Conflicts returns the strings in all domains that were found with different plural forms
+func (h *MsgHolder) Conflicts() []Conflict {
+	var conflicts []Conflict
+	for _, domain := range h.Domains() {
+		_, c := h.Messages(domain)
+		conflicts = append(conflicts, c...)
+	}
+	return conflicts
+}
+
+// Template returns the message template for domain, starting with the default header entry
+func (h *MsgHolder) Template(domain string, opts TemplateOptions) *po.File {
+	f := po.NewTemplate()
+	messages, _ := h.Messages(domain)
+	for _, m := range messages {
+		var references []string
+		for _, o := range m.Occurrences {
+			references = appendUnique(references, o.Reference(opts.Columns))
+		}
+
+		f.Entries = append(f.Entries, &po.Entry{
+			ExtractedComments: m.Comments,
+			References:        references,
+			Flags:             m.Flags,
+			Context:           m.Context,
+			ID:                m.Singular,
+			IDPlural:          m.Plural,
+		})
+	}
+	return f
+}
+
+// Templates returns the message templates for all domains, keyed by domain
+func (h *MsgHolder) Templates(opts TemplateOptions) map[string]*po.File {
+	templates := map[string]*po.File{}
+	for _, domain := range h.Domains() {
+		templates[domain] = h.Template(domain, opts)
+	}
+	return templates
+}
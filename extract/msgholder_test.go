@@ -0,0 +1,84 @@
+package extract
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsgHolderTemplate(t *testing.T) {
+	msgHolder := NewMsgHolder()
+	msgHolder.Add(TranslationString{Path: "b.go", Line: 2, Singular: "Second"})
+	msgHolder.Add(TranslationString{Path: "b.go", Line: 10, Singular: "Third"})
+	msgHolder.Add(TranslationString{Path: "a.go", Line: 1, Column: 5, Singular: "First", Plural: "Firsts", Flags: []string{"go-format"}})
+	msgHolder.Add(TranslationString{Path: "a.go", Line: 3, Singular: ""})
+	msgHolder.Add(TranslationString{Path: "b.go", Line: 20, Singular: "First", Comments: []string{"TRANSLATORS: Title"}, Flags: []string{"go-format"}})
+	msgHolder.Add(TranslationString{Path: "c.go", Line: 1, Singular: "Admin", Domain: "admin"})
+
+	require.Equal(t, []string{"admin", "default"}, msgHolder.Domains())
+
+	f := msgHolder.Template("default", TemplateOptions{})
+	require.Len(t, f.Entries, 4)
+	require.True(t, f.Entries[0].IsHeader())
+
+	require.Equal(t, "First", f.Entries[1].ID)
+	require.Equal(t, "Firsts", f.Entries[1].IDPlural)
+	require.Equal(t, []string{"a.go:1", "b.go:20"}, f.Entries[1].References)
+	require.Equal(t, []string{"TRANSLATORS: Title"}, f.Entries[1].ExtractedComments)
+	require.Equal(t, []string{"go-format"}, f.Entries[1].Flags)
+
+	require.Equal(t, "Second", f.Entries[2].ID)
+	require.Equal(t, []string{"b.go:2"}, f.Entries[2].References)
+	require.Equal(t, "Third", f.Entries[3].ID)
+
+	f = msgHolder.Template("default", TemplateOptions{Columns: true})
+	require.Equal(t, []string{"a.go:1:5", "b.go:20:0"}, f.Entries[1].References)
+}
+
+func TestMsgHolderConflictingFlags(t *testing.T) {
+	msgHolder := NewMsgHolder()
+	msgHolder.Add(TranslationString{Path: "b.go", Line: 1, Singular: "%d%%", Flags: []string{"go-format"}})
+	msgHolder.Add(TranslationString{Path: "a.go", Line: 1, Singular: "%d%%", Flags: []string{"no-go-format"}})
+	msgHolder.Add(TranslationString{Path: "c.go", Line: 1, Singular: "%d%%", Flags: []string{"go-format"}})
+
+	messages, _ := msgHolder.Messages("default")
+	require.Len(t, messages, 1)
+	require.Equal(t, []string{"no-go-format"}, messages[0].Flags)
+}
+
+func TestMsgHolderRemove(t *testing.T) {
+	msgHolder := NewMsgHolder()
+	msgHolder.Add(TranslationString{Path: "a.go", Line: 1, Singular: "First"})
+	msgHolder.Add(TranslationString{Path: "b.go", Line: 1, Singular: "Second"})
+	msgHolder.Add(TranslationString{Path: "b.go", Line: 2, Singular: "Admin", Domain: "admin"})
+
+	domains := msgHolder.Remove(func(s TranslationString) bool { return s.Path == "b.go" })
+	require.Equal(t, []string{"admin", "default"}, domains)
+	require.Equal(t, []string{"default"}, msgHolder.Domains())
+	require.Len(t, msgHolder.Strings("default"), 1)
+
+	require.Empty(t, msgHolder.Remove(func(s TranslationString) bool { return s.Path == "c.go" }))
+}
+
+func TestMsgHolderConcurrentAdd(t *testing.T) {
+	msgHolder := NewMsgHolder()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := 1; line <= 100; line++ {
+				msgHolder.Add(TranslationString{Path: fmt.Sprintf("file%d.html", i), Line: line, Singular: "String"})
+			}
+		}()
+	}
+	wg.Wait()
+
+	strs := msgHolder.Strings("default")
+	require.Len(t, strs, 1000)
+	require.Equal(t, "file0.html:1", strs[0].Reference(false))
+	require.Equal(t, "file9.html:100", strs[999].Reference(false))
+}
@@ -0,0 +1,769 @@
+package extract
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yzzyx/makemessage/internal/format"
+	"golang.org/x/tools/go/packages"
+)
+
+// FuncDef describes a single function and it's arguments
+type FuncDef struct {
+	Name      string    // Name of function or method (e.g. "Get" or "GetN")
+	Arguments []ArgType // List of expected arguments
+}
+
+// A Keyword defines how the parser should be able to locate translation functions.
+//
+// A call is matched if the name of the called function is listed in Functions, and
+//   - it is a package-level function declared in one of the packages in Paths,
+//   - it is a method of one of the types in Types (regardless of pointer receivers and type arguments), or
+//   - it is an interface method, also when called through a type parameter, and the interface
+//     is either listed in Types, or is implemented by one of the types in Types.
+//
+// If both Paths and Types are empty, functions with a matching name are matched in any package.
+type Keyword struct {
+	Paths     []string  // Import paths of packages declaring translation functions
+	Types     []string  // Types declaring translation methods, as "import/path.TypeName"
+	Functions []FuncDef // List of functions that handle translations
+}
+
+// DefaultKeywords are the translation functions looked for when no keywords are given
+var DefaultKeywords = []Keyword{
+	{
+		Paths: []string{"github.com/leonelquinteros/gotext"},
+		Types: []string{"github.com/leonelquinteros/gotext.Locale",
+			"github.com/leonelquinteros/gotext.Mo",
+			"github.com/leonelquinteros/gotext.Po"},
+		Functions: []FuncDef{
+			{Name: "Get", Arguments: []ArgType{ArgTypeSingular}},
+			{Name: "GetN", Arguments: []ArgType{ArgTypeSingular, ArgTypePlural}},
+			{Name: "GetD", Arguments: []ArgType{ArgTypeDomain, ArgTypeSingular}},
+			{Name: "GetND", Arguments: []ArgType{ArgTypeDomain, ArgTypeSingular, ArgTypePlural}},
+			{Name: "GetC", Arguments: []ArgType{ArgTypeSingular, ArgTypeContext}},
+			{Name: "GetNC", Arguments: []ArgType{ArgTypeSingular, ArgTypePlural, ArgTypeSkip, ArgTypeContext}},
+			{Name: "GetDC", Arguments: []ArgType{ArgTypeDomain, ArgTypeSingular, ArgTypeContext}},
+			{Name: "GetNDC", Arguments: []ArgType{ArgTypeDomain, ArgTypeSingular, ArgTypePlural, ArgTypeSkip, ArgTypeContext}},
+		},
+	},
+	{
+		// No-op markers, used to mark strings that are translated later on.
+		// These are matched regardless of which package they are declared in.
+		Functions: []FuncDef{
+			{Name: "N_", Arguments: []ArgType{ArgTypeSingular}},
+			{Name: "gettext_noop", Arguments: []ArgType{ArgTypeSingular}},
+			{Name: "pgettext_noop", Arguments: []ArgType{ArgTypeContext, ArgTypeSingular}},
+		},
+	},
+}
+
+type visitor struct {
+	basePath  string    // Directory we started from
+	keywords  []Keyword // Translation functions to look for
+	typeIndex typeIndex // All packages known to the type checker, used to resolve Keyword.Types
+	msgHolder *MsgHolder
+	pkg       *packages.Package
+	comments  map[int]string // Comments in the current file, keyed by the line they end on
+	domain    string         // Domain of strings without one, from the domain rules
+}
+
+// fileComments returns the raw text of all comment groups in file, keyed by the line they end on
+func fileComments(fset *token.FileSet, file *ast.File) map[int]string {
+	comments := map[int]string{}
+	for _, group := range file.Comments {
+		line := fset.Position(group.End()).Line
+		for _, c := range group.List {
+			comments[line] += c.Text + "\n"
+		}
+	}
+	return comments
+}
+
+// translatorComments returns the lines of comment starting at the first line beginning with
+// "TRANSLATORS:", with comment markers removed
+func translatorComments(comment string) []string {
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "//")
+		line = strings.TrimPrefix(line, "/*")
+		line = strings.TrimSuffix(line, "*/")
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+
+		if lines == nil && !strings.HasPrefix(strings.ToUpper(line), "TRANSLATORS:") {
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// typeIndex maps import paths to type-checked packages
+type typeIndex map[string]*types.Package
+
+// add adds pkg and all packages imported by it to the index
+func (idx typeIndex) add(pkg *types.Package) {
+	if pkg == nil || idx[pkg.Path()] != nil {
+		return
+	}
+	idx[pkg.Path()] = pkg
+	for _, imp := range pkg.Imports() {
+		idx.add(imp)
+	}
+}
+
+// lookup returns the type named by name ("import/path.TypeName"), or nil if it is not known
+func (idx typeIndex) lookup(name string) types.Type {
+	dot := strings.LastIndex(name, ".")
+	if dot == -1 {
+		return nil
+	}
+
+	pkg := idx[name[:dot]]
+	if pkg == nil {
+		return nil
+	}
+
+	typeName, ok := pkg.Scope().Lookup(name[dot+1:]).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	return typeName.Type()
+}
+
+// ArgType describes how an argument of a translation function is used
+type ArgType int
+
+const (
+	ArgTypeSingular ArgType = iota
+	ArgTypePlural
+	ArgTypeContext
+	ArgTypeDomain
+	ArgTypeSkip
+)
+
+func (a ArgType) String() string {
+	switch a {
+	case ArgTypeSingular:
+		return "singular"
+	case ArgTypePlural:
+		return "plural"
+	case ArgTypeContext:
+		return "context"
+	case ArgTypeDomain:
+		return "domain"
+	default:
+		return "skip"
+	}
+}
+
+// ArgTypeFromString returns the ArgType named by s, e.g. "singular" or "ctx"
+func ArgTypeFromString(s string) (ArgType, error) {
+	switch strings.ToLower(s) {
+	case "singular", "single":
+		return ArgTypeSingular, nil
+	case "plural":
+		return ArgTypePlural, nil
+	case "context", "ctx":
+		return ArgTypeContext, nil
+	case "domain", "dom":
+		return ArgTypeDomain, nil
+	case "skip":
+		return ArgTypeSkip, nil
+	default:
+		return ArgTypeSkip, fmt.Errorf("unknown argument type '%s'", s)
+	}
+}
+
+// ParseKeyword parses a keyword specification in the form "[path.]Name[:argtype,...]"
+// or "(path.Type).Name[:argtype,...]", e.g. "T", "github.com/acme/i18n.T:singular,plural"
+// or "(*github.com/acme/web.Request).T". Pointer receivers are accepted, but not required.
+//
+// If no package or type is given, functions with the given name are matched in any package.
+// If no argument types are given, the first argument is used as the singular form.
+func ParseKeyword(spec string) (Keyword, error) {
+	var pkg Keyword
+
+	name := spec
+	arguments := []ArgType{ArgTypeSingular}
+
+	if idx := strings.LastIndex(spec, ":"); idx != -1 {
+		name = spec[:idx]
+		arguments = nil
+		for _, s := range strings.Split(spec[idx+1:], ",") {
+			at, err := ArgTypeFromString(strings.TrimSpace(s))
+			if err != nil {
+				return Keyword{}, fmt.Errorf("keyword '%s' has an %w", spec, err)
+			}
+			arguments = append(arguments, at)
+		}
+	}
+
+	if strings.HasPrefix(name, "(") {
+		end := strings.Index(name, ").")
+		if end == -1 {
+			return Keyword{}, fmt.Errorf("keyword '%s' has an invalid receiver type", spec)
+		}
+		pkg.Types = []string{strings.TrimPrefix(name[1:end], "*")}
+		name = name[end+2:]
+	} else if idx := strings.LastIndex(name, "."); idx != -1 {
+		pkg.Paths = []string{name[:idx]}
+		name = name[idx+1:]
+	}
+
+	if name == "" {
+		return Keyword{}, fmt.Errorf("keyword '%s' does not specify a function name", spec)
+	}
+
+	pkg.Functions = []FuncDef{{Name: name, Arguments: arguments}}
+	return pkg, nil
+}
+
+// entryParses parses a single GetX-call
+type entryParser struct {
+	basePath string // Directory we started from
+
+	// gotext argument types (singular/plural/context/domain)
+	argumentTypes []ArgType
+	msgHolder     *MsgHolder
+
+	// Format flag overrides from comments, e.g. "no-go-format"
+	overrides []string
+
+	// Comments for translators, from comments starting with "TRANSLATORS:"
+	comments []string
+
+	// Domain of the string if none is given in the call
+	defaultDomain string
+
+	// Filled when run
+	parsedFuncName bool
+	currentArg     int
+	mismatch       bool
+
+	path     string
+	line     int
+	column   int
+	singular string
+	plural   string
+	context  string
+	domain   string
+}
+
+func (e *entryParser) Visit(node ast.Node) ast.Visitor {
+	// First argument is the function name
+	if !e.parsedFuncName {
+		e.parsedFuncName = true
+		return nil
+	}
+
+	currentArg := e.currentArg
+	e.currentArg++
+
+	// We're not interested in the other arguments
+	if currentArg > len(e.argumentTypes)-1 || e.mismatch {
+		return nil
+	}
+
+	// Skip this argument
+	if e.argumentTypes[currentArg] == ArgTypeSkip {
+		return nil
+	}
+
+	basic, ok := node.(*ast.BasicLit)
+	if !ok || basic.Kind != token.STRING {
+		e.mismatch = true
+		return nil
+	}
+
+	strVal := basic.Value
+	quote := strVal[0:1]
+	strVal = strings.TrimSuffix(strings.TrimPrefix(strVal, quote), quote)
+
+	switch e.argumentTypes[currentArg] {
+	case ArgTypeSingular:
+		e.singular = strVal
+	case ArgTypePlural:
+		e.plural = strVal
+	case ArgTypeDomain:
+		e.domain = strVal
+	case ArgTypeContext:
+		e.context = strVal
+	}
+
+	if currentArg == len(e.argumentTypes)-1 {
+		domain := e.domain
+		if domain == "" {
+			domain = e.defaultDomain
+		}
+
+		e.msgHolder.Add(TranslationString{
+			Path:     strings.TrimPrefix(strings.TrimPrefix(e.path, e.basePath), "/"),
+			Line:     e.line,
+			Column:   e.column,
+			Singular: e.singular,
+			Plural:   e.plural,
+			Context:  e.context,
+			Domain:   domain,
+			Comments: e.comments,
+			Flags:    format.ApplyOverrides(format.GoFlags(e.singular, e.plural), e.overrides),
+		})
+		return nil
+	}
+
+	return nil
+}
+
+// calledFunc tries to get the function object that a call expression refers to.
+// Both qualified calls like "a.b()" and plain calls like "b()" are handled,
+// the latter covering dot-imported packages and functions in the same package.
+//
+// The final return value will be false if the call does not refer to a
+// declared function (e.g. if it is a call to a function variable or a conversion).
+func (v *visitor) calledFunc(call *ast.CallExpr) (*types.Func, bool) {
+	var ident *ast.Ident
+
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	case *ast.Ident:
+		ident = fun
+	default:
+		return nil, false
+	}
+
+	fn, ok := v.pkg.TypesInfo.Uses[ident].(*types.Func)
+	if !ok {
+		return nil, false
+	}
+
+	return fn, true
+}
+
+// lookup returns the argument types of fn if it is one of the translation functions in pkg
+func (pkg Keyword) lookup(fn *types.Func, idx typeIndex) []ArgType {
+	var arguments []ArgType
+	for _, f := range pkg.Functions {
+		if fn.Name() == f.Name {
+			arguments = f.Arguments
+			break
+		}
+	}
+
+	if arguments == nil || pkg.matches(fn, idx) {
+		return arguments
+	}
+	return nil
+}
+
+// matches checks if fn is declared in one of the packages or types of pkg,
+// as described in the documentation for Keyword
+func (pkg Keyword) matches(fn *types.Func, idx typeIndex) bool {
+	if len(pkg.Paths) == 0 && len(pkg.Types) == 0 {
+		return true
+	}
+
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		for _, path := range pkg.Paths {
+			if fn.Pkg() != nil && fn.Pkg().Path() == path {
+				return true
+			}
+		}
+		return false
+	}
+
+	recvType := recv.Type()
+	if ptr, ok := recvType.(*types.Pointer); ok {
+		recvType = ptr.Elem()
+	}
+
+	if named, ok := types.Unalias(recvType).(*types.Named); ok {
+		obj := named.Origin().Obj()
+		if obj.Pkg() != nil {
+			for _, t := range pkg.Types {
+				if obj.Pkg().Path()+"."+obj.Name() == t {
+					return true
+				}
+			}
+		}
+	}
+
+	iface, ok := recvType.Underlying().(*types.Interface)
+	if !ok {
+		return false
+	}
+
+	for _, t := range pkg.Types {
+		typ := idx.lookup(t)
+		if typ == nil {
+			continue
+		}
+
+		if types.Implements(typ, iface) || types.Implements(types.NewPointer(typ), iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the argument types of fn if it is one of the known translation functions
+func (v *visitor) lookup(fn *types.Func) []ArgType {
+	for _, pkg := range v.keywords {
+		if argumentTypes := pkg.lookup(fn, v.typeIndex); argumentTypes != nil {
+			return argumentTypes
+		}
+	}
+	return nil
+}
+
+func (v *visitor) Visit(node ast.Node) ast.Visitor {
+	call, ok := node.(*ast.CallExpr)
+	if !ok {
+		return v
+	}
+
+	fn, ok := v.calledFunc(call)
+	if !ok {
+		return v
+	}
+
+	argumentTypes := v.lookup(fn)
+	if argumentTypes == nil {
+		return v
+	}
+
+	// Overrides and translator comments may be given on the same line as the call, or on the line before it
+	var overrides, comments []string
+	pos := v.pkg.Fset.Position(call.Pos())
+	for _, line := range []int{pos.Line - 1, pos.Line} {
+		overrides = append(overrides, format.Overrides(v.comments[line])...)
+		comments = append(comments, translatorComments(v.comments[line])...)
+	}
+
+	return &entryParser{
+		basePath:      v.basePath,
+		argumentTypes: argumentTypes,
+		msgHolder:     v.msgHolder,
+		overrides:     overrides,
+		comments:      comments,
+		defaultDomain: v.domain,
+		path:          pos.Filename,
+		line:          pos.Line,
+		column:        pos.Column,
+	}
+}
+
+// wrappedArguments checks if the body of fn passes any of the string parameters of fn
+// directly on to a translation function. If so, the argument types of fn are returned,
+// with parameters that are not passed on marked as skipped.
+func (v *visitor) wrappedArguments(fn *types.Func, body *ast.BlockStmt) []ArgType {
+	sig := fn.Type().(*types.Signature)
+
+	params := map[*types.Var]int{}
+	for i := 0; i < sig.Params().Len(); i++ {
+		param := sig.Params().At(i)
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			break
+		}
+		if types.Identical(param.Type(), types.Typ[types.String]) {
+			params[param] = i
+		}
+	}
+
+	if len(params) == 0 {
+		return nil
+	}
+
+	var arguments []ArgType
+	ast.Inspect(body, func(node ast.Node) bool {
+		if arguments != nil {
+			return false
+		}
+
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		callee, ok := v.calledFunc(call)
+		if !ok || callee == fn {
+			return true
+		}
+
+		argumentTypes := v.lookup(callee)
+		if argumentTypes == nil {
+			return true
+		}
+
+		mapped := map[int]ArgType{}
+		maxIdx := -1
+		for i, at := range argumentTypes {
+			if at == ArgTypeSkip || i >= len(call.Args) {
+				continue
+			}
+
+			ident, ok := call.Args[i].(*ast.Ident)
+			if !ok {
+				continue
+			}
+
+			param, ok := v.pkg.TypesInfo.Uses[ident].(*types.Var)
+			if !ok {
+				continue
+			}
+
+			if idx, ok := params[param]; ok {
+				mapped[idx] = at
+				if idx > maxIdx {
+					maxIdx = idx
+				}
+			}
+		}
+
+		hasSingular := false
+		for _, at := range mapped {
+			hasSingular = hasSingular || at == ArgTypeSingular
+		}
+
+		if !hasSingular {
+			return true
+		}
+
+		arguments = make([]ArgType, maxIdx+1)
+		for i := range arguments {
+			arguments[i] = ArgTypeSkip
+			if at, ok := mapped[i]; ok {
+				arguments[i] = at
+			}
+		}
+		return false
+	})
+
+	return arguments
+}
+
+// detectWrappers finds functions in pkgs that pass their string parameters directly on
+// to one of the translation functions in keywords, and returns them as new keywords.
+// Since wrappers may in turn be wrapped, this is repeated until no more wrappers are found.
+// Each wrapper found is reported to log, if set.
+func detectWrappers(pkgs []*packages.Package, keywords []Keyword, idx typeIndex, log io.Writer) []Keyword {
+	var wrappers []Keyword
+	found := map[*types.Func]bool{}
+
+	for {
+		foundCount := len(wrappers)
+
+		for _, pkg := range pkgs {
+			v := &visitor{
+				keywords:  append(keywords[:len(keywords):len(keywords)], wrappers...),
+				typeIndex: idx,
+				pkg:       pkg,
+			}
+
+			for _, astFile := range pkg.Syntax {
+				for _, decl := range astFile.Decls {
+					funcDecl, ok := decl.(*ast.FuncDecl)
+					if !ok || funcDecl.Body == nil {
+						continue
+					}
+
+					fn, ok := pkg.TypesInfo.Defs[funcDecl.Name].(*types.Func)
+					if !ok || found[fn] || v.lookup(fn) != nil {
+						continue
+					}
+
+					arguments := v.wrappedArguments(fn, funcDecl.Body)
+					if arguments == nil {
+						continue
+					}
+
+					found[fn] = true
+					wrapper := Keyword{Functions: []FuncDef{{Name: fn.Name(), Arguments: arguments}}}
+					if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+						recvType := recv.Type()
+						if ptr, ok := recvType.(*types.Pointer); ok {
+							recvType = ptr.Elem()
+						}
+						named, ok := types.Unalias(recvType).(*types.Named)
+						if !ok {
+							continue
+						}
+						obj := named.Origin().Obj()
+						wrapper.Types = []string{obj.Pkg().Path() + "." + obj.Name()}
+					} else {
+						wrapper.Paths = []string{fn.Pkg().Path()}
+					}
+					wrappers = append(wrappers, wrapper)
+
+					if log == nil {
+						continue
+					}
+					names := make([]string, len(arguments))
+					for i, at := range arguments {
+						names[i] = at.String()
+					}
+					fmt.Fprintf(log, "detected translation wrapper %s:%s\n", fn.FullName(), strings.Join(names, ","))
+				}
+			}
+		}
+
+		if len(wrappers) == foundCount {
+			return wrappers
+		}
+	}
+}
+
+// GoOptions controls how go packages are parsed
+type GoOptions struct {
+	// Keywords are the translation functions to look for. If nil, DefaultKeywords are used.
+	Keywords []Keyword
+
+	// DetectWrappers treats functions passing their arguments on to translation functions as keywords
+	DetectWrappers bool
+
+	// Cache, if set, stores the strings found in each package, so that packages whose files haven't
+	// changed are not loaded again. It's not used with DetectWrappers, as a wrapper found in one
+	// package changes the strings found in others.
+	Cache *Cache
+
+	// Domains selects the domain of strings found in packages, if none is given in the call
+	Domains DomainRules
+
+	// Timings, if set, records the time spent loading packages and finding strings in them
+	Timings *Timings
+
+	// Log, if set, receives a line for each translation wrapper found with DetectWrappers
+	Log io.Writer
+}
+
+// loadMode is what is loaded for the parsed packages. Only the parsed packages are type-checked
+// from source, while their dependencies are read from export data, which is much faster.
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
+	packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports
+
+// ParseGo loads the packages matching patterns, relative to basePath, and adds all
+// translation strings found in them to msgHolder
+func ParseGo(basePath string, patterns []string, msgHolder *MsgHolder, opts GoOptions) error {
+	keywords := opts.Keywords
+	if keywords == nil {
+		keywords = DefaultKeywords
+	}
+
+	cache := opts.Cache
+	if opts.DetectWrappers {
+		cache = nil
+	}
+
+	// Packages given by their folder can be read from the cache, if none of their files have changed
+	start := time.Now()
+	keys := map[string]string{} // Cache keys of the packages to load, by folder
+	var load []string
+	for _, pattern := range patterns {
+		dir, ok := patternDir(basePath, pattern)
+		if cache == nil || !ok {
+			load = append(load, pattern)
+			continue
+		}
+
+		// If the folder cannot be read, any errors are left to the loader
+		key, err := packageKey(basePath, dir, keywords, opts.Domains.Packages)
+		if err != nil || key == "" {
+			load = append(load, pattern)
+			continue
+		}
+
+		if strs, ok := cache.get(key); ok {
+			for _, s := range strs {
+				msgHolder.Add(s)
+			}
+			continue
+		}
+		keys[dir] = key
+		load = append(load, pattern)
+	}
+	if cache != nil {
+		opts.Timings.Since("read go cache", start)
+	}
+
+	if len(load) == 0 {
+		return nil
+	}
+
+	cfg := &packages.Config{
+		Mode: loadMode,
+		Dir:  basePath,
+	}
+
+	start = time.Now()
+	packages, err := packages.Load(cfg, load...)
+	if err != nil {
+		return err
+	}
+	opts.Timings.Since("load go packages", start)
+
+	idx := typeIndex{}
+	for _, pkg := range packages {
+		idx.add(pkg.Types)
+	}
+
+	if opts.DetectWrappers {
+		start = time.Now()
+		keywords = append(keywords[:len(keywords):len(keywords)], detectWrappers(packages, keywords, idx, opts.Log)...)
+		opts.Timings.Since("detect wrappers", start)
+	}
+
+	start = time.Now()
+	defer opts.Timings.Since("find go strings", start)
+	for _, pkg := range packages {
+		// Packages with errors are not cached, as the errors may be caused by other packages
+		var key string
+		if len(pkg.GoFiles) > 0 && len(pkg.Errors) == 0 {
+			key = keys[filepath.Dir(pkg.GoFiles[0])]
+		}
+
+		pkgHolder := msgHolder
+		if key != "" {
+			pkgHolder = NewMsgHolder()
+		}
+
+		v := &visitor{
+			basePath:  basePath,
+			keywords:  keywords,
+			typeIndex: idx,
+			msgHolder: pkgHolder,
+			pkg:       pkg,
+			domain:    opts.Domains.PackageDomain(pkg.PkgPath),
+		}
+
+		for _, astFile := range v.pkg.Syntax {
+			v.comments = fileComments(pkg.Fset, astFile)
+			ast.Walk(v, astFile)
+		}
+
+		if key != "" {
+			var strs []TranslationString
+			for _, domain := range pkgHolder.Domains() {
+				strs = append(strs, pkgHolder.Strings(domain)...)
+			}
+
+			for _, s := range strs {
+				msgHolder.Add(s)
+			}
+
+			err = cache.put(key, strs)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
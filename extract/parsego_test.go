@@ -0,0 +1,175 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGo(t *testing.T) {
+	msgHolder := NewMsgHolder()
+
+	cwd, _ := os.Getwd()
+	basePath := filepath.Join(cwd, "testdata")
+	err := ParseGo(basePath, []string{"."}, msgHolder, GoOptions{})
+	if err != nil {
+		t.Fatalf("ParseGo returned error: %v", err)
+		return
+	}
+
+	defaultDom, ok := msgHolder.strings["default"]
+	require.True(t, ok, "Expected default domain to be present")
+
+	messageMap := map[string]bool{}
+	references := map[string]string{}
+	for _, msg := range defaultDom {
+		messageMap[msg.Singular] = true
+		references[msg.Singular] = msg.Reference(true)
+	}
+
+	require.True(t, messageMap["String from gotext package"], "expected to find string in messages")
+	require.Equal(t, "gotext.go:7:2", references["String from gotext package"])
+	require.Equal(t, "gotext.go:10:2", references["String from gotext.Locale"])
+	require.True(t, messageMap["String from gotext.Locale"], "expected to find string in messages")
+	require.True(t, messageMap["String from gotext.Po"], "expected to find string in messages")
+	require.True(t, messageMap["String from gotext.Mo"], "expected to find string in messages")
+}
+
+func TestParseGoPlainCalls(t *testing.T) {
+	msgHolder := NewMsgHolder()
+
+	keyword, err := ParseKeyword("T")
+	require.Nil(t, err)
+
+	keywords := append(DefaultKeywords[:len(DefaultKeywords):len(DefaultKeywords)], keyword)
+
+	cwd, _ := os.Getwd()
+	basePath := filepath.Join(cwd, "testdata")
+	err = ParseGo(basePath, []string{"./markers"}, msgHolder, GoOptions{Keywords: keywords})
+	require.Nil(t, err)
+
+	messageMap := map[string]bool{}
+	for _, msg := range msgHolder.strings["default"] {
+		messageMap[msg.Singular] = true
+	}
+
+	require.True(t, messageMap["String from N_ marker"], "expected to find string in messages")
+	require.True(t, messageMap["String from dot-import"], "expected to find string in messages")
+	require.True(t, messageMap["String from local keyword"], "expected to find string in messages")
+}
+
+func TestParseKeyword(t *testing.T) {
+	pkg, err := ParseKeyword("(*github.com/acme/web.Request).T:singular,plural")
+	require.Nil(t, err)
+	require.Equal(t, []string{"github.com/acme/web.Request"}, pkg.Types)
+	require.Nil(t, pkg.Paths)
+	require.Equal(t, []FuncDef{{Name: "T", Arguments: []ArgType{ArgTypeSingular, ArgTypePlural}}}, pkg.Functions)
+
+	pkg, err = ParseKeyword("gopkg.in/acme/i18n.v2.TC:context,singular")
+	require.Nil(t, err)
+	require.Equal(t, []string{"gopkg.in/acme/i18n.v2"}, pkg.Paths)
+	require.Equal(t, []FuncDef{{Name: "TC", Arguments: []ArgType{ArgTypeContext, ArgTypeSingular}}}, pkg.Functions)
+
+	pkg, err = ParseKeyword("N_")
+	require.Nil(t, err)
+	require.Nil(t, pkg.Paths)
+	require.Nil(t, pkg.Types)
+	require.Equal(t, []FuncDef{{Name: "N_", Arguments: []ArgType{ArgTypeSingular}}}, pkg.Functions)
+
+	_, err = ParseKeyword("github.com/acme/i18n.")
+	require.NotNil(t, err)
+}
+
+func TestParseGoWrappers(t *testing.T) {
+	msgHolder := NewMsgHolder()
+
+	cwd, _ := os.Getwd()
+	basePath := filepath.Join(cwd, "testdata")
+	err := ParseGo(basePath, []string{"./wrappers"}, msgHolder, GoOptions{DetectWrappers: true})
+	require.Nil(t, err)
+
+	messages := map[string]TranslationString{}
+	for _, msg := range msgHolder.strings["default"] {
+		messages[msg.Singular] = msg
+	}
+
+	require.Contains(t, messages, "String from wrapper")
+	require.Contains(t, messages, "String from wrapped wrapper")
+	require.Contains(t, messages, "Singular from wrapper")
+	require.Equal(t, "Plural from wrapper", messages["Singular from wrapper"].Plural)
+	require.Contains(t, messages, "String from wrapper with context")
+	require.Equal(t, "wrapctx", messages["String from wrapper with context"].Context)
+}
+
+func TestParseGoInterfaces(t *testing.T) {
+	msgHolder := NewMsgHolder()
+
+	keyword, err := ParseKeyword("(github.com/yzzyx/makemessage/testdata/iface.Named).Tr")
+	require.Nil(t, err)
+
+	keywords := append(DefaultKeywords[:len(DefaultKeywords):len(DefaultKeywords)], keyword)
+
+	cwd, _ := os.Getwd()
+	basePath := filepath.Join(cwd, "testdata")
+	err = ParseGo(basePath, []string{"./iface"}, msgHolder, GoOptions{Keywords: keywords})
+	require.Nil(t, err)
+
+	messageMap := map[string]bool{}
+	for _, msg := range msgHolder.strings["default"] {
+		messageMap[msg.Singular] = true
+	}
+
+	require.True(t, messageMap["String through interface"], "expected to find string in messages")
+	require.True(t, messageMap["String through type parameter"], "expected to find string in messages")
+	require.True(t, messageMap["String through named interface"], "expected to find string in messages")
+	require.True(t, messageMap["String through gotext.Translator"], "expected to find string in messages")
+	require.False(t, messageMap["Not a translation"], "did not expect to find string in messages")
+}
+
+func TestParseGoFormatFlags(t *testing.T) {
+	msgHolder := NewMsgHolder()
+
+	cwd, _ := os.Getwd()
+	basePath := filepath.Join(cwd, "testdata")
+	err := ParseGo(basePath, []string{"./format"}, msgHolder, GoOptions{})
+	require.Nil(t, err)
+
+	flags := map[string][]string{}
+	for _, msg := range msgHolder.strings["default"] {
+		flags[msg.Singular] = msg.Flags
+	}
+
+	require.Equal(t, []string{"go-format"}, flags["Hello %s, you have %d messages"])
+	require.Empty(t, flags["Completed 100%%"])
+	require.Equal(t, []string{"go-format"}, flags["One file"])
+	require.Equal(t, []string{"no-go-format"}, flags["Literal %s"])
+	require.Equal(t, []string{"go-format"}, flags["Forced format"])
+}
+
+func TestParseGoComments(t *testing.T) {
+	msgHolder := NewMsgHolder()
+
+	cwd, _ := os.Getwd()
+	basePath := filepath.Join(cwd, "testdata")
+	err := ParseGo(basePath, []string{"./comments"}, msgHolder, GoOptions{})
+	require.Nil(t, err)
+
+	messages, conflicts := msgHolder.Messages("default")
+	require.Len(t, messages, 3)
+
+	require.Equal(t, "Sign in", messages[0].Singular)
+	require.Equal(t, []string{"TRANSLATORS: Button on the login page", "TRANSLATORS: Link in the menu"}, messages[0].Comments)
+	require.Len(t, messages[0].Occurrences, 2)
+
+	require.Equal(t, "Sign out", messages[1].Singular)
+	require.Empty(t, messages[1].Comments)
+
+	require.Equal(t, "One message", messages[2].Singular)
+	require.Equal(t, "%d messages", messages[2].Plural)
+	require.Equal(t, []string{"TRANSLATORS: Shown when the user", "has new messages"}, messages[2].Comments)
+
+	require.Len(t, conflicts, 1)
+	require.Equal(t, `comments/comments.go:21: msgid "One message" has msgid_plural "%d new messages", but "%d messages" in comments/comments.go:20`, conflicts[0].String())
+}
@@ -0,0 +1,159 @@
+package extract
+
+import (
+	"text/template/parse"
+
+	"github.com/yzzyx/makemessage/internal/format"
+)
+
+// goTemplateFunctions lists the template functions extracted from go templates, e.g. {{ gettext "Hello" }}
+var goTemplateFunctions = map[string][]ArgType{
+	"gettext":   {ArgTypeSingular},
+	"ngettext":  {ArgTypeSingular, ArgTypePlural},
+	"pgettext":  {ArgTypeContext, ArgTypeSingular},
+	"npgettext": {ArgTypeContext, ArgTypeSingular, ArgTypePlural},
+	"T":         {ArgTypeSingular},
+}
+
+// GoTemplateExtractor extracts strings from text/template and html/template files. Only calls to template
+// functions are matched, not methods, so that e.g. {{ .Query.Get "page" }} is not taken to be a translation.
+type GoTemplateExtractor struct {
+	// Keywords are additional template functions. Only keywords without a package or type, as given
+	// by --keyword Name[:argtype,...], are used, as template functions don't belong to either.
+	Keywords []Keyword
+}
+
+// functions returns the template functions to extract, with their argument types
+func (e GoTemplateExtractor) functions() map[string][]ArgType {
+	functions := map[string][]ArgType{}
+	for name, args := range goTemplateFunctions {
+		functions[name] = args
+	}
+
+	for _, k := range e.Keywords {
+		if len(k.Paths) > 0 || len(k.Types) > 0 {
+			continue
+		}
+		for _, fn := range k.Functions {
+			functions[fn.Name] = fn.Arguments
+		}
+	}
+	return functions
+}
+
+func (GoTemplateExtractor) Extensions() []string {
+	return []string{".tmpl", ".gotmpl"}
+}
+
+func (g GoTemplateExtractor) Extract(path string, content []byte) ([]TranslationString, error) {
+	trees := map[string]*parse.Tree{}
+	t := parse.New(path)
+	t.Mode = parse.SkipFuncCheck
+	_, err := t.Parse(string(content), "", "", trees)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &goTemplateParser{path: path, lines: &lineCounter{content: string(content)}, functions: g.functions()}
+	for _, tree := range trees {
+		if tree.Root != nil {
+			e.walk(tree.Root)
+		}
+	}
+	return e.strs, nil
+}
+
+type goTemplateParser struct {
+	path      string
+	lines     *lineCounter
+	functions map[string][]ArgType
+	strs      []TranslationString
+}
+
+func (e *goTemplateParser) walk(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			e.walk(child)
+		}
+	case *parse.ActionNode:
+		e.walk(n.Pipe)
+	case *parse.IfNode:
+		e.walk(n.Pipe)
+		e.walk(n.List)
+		e.walk(n.ElseList)
+	case *parse.RangeNode:
+		e.walk(n.Pipe)
+		e.walk(n.List)
+		e.walk(n.ElseList)
+	case *parse.WithNode:
+		e.walk(n.Pipe)
+		e.walk(n.List)
+		e.walk(n.ElseList)
+	case *parse.TemplateNode:
+		e.walk(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			e.walk(cmd)
+		}
+	case *parse.CommandNode:
+		e.command(n)
+		for _, arg := range n.Args {
+			e.walk(arg)
+		}
+	}
+}
+
+// funcName returns the name of the template function called by a command, or an empty string
+// if it calls a method or doesn't call anything
+func funcName(node parse.Node) string {
+	if n, ok := node.(*parse.IdentifierNode); ok {
+		return n.Ident
+	}
+	return ""
+}
+
+// command extracts the strings from a single command, if it calls a translation function
+func (e *goTemplateParser) command(cmd *parse.CommandNode) {
+	if len(cmd.Args) == 0 {
+		return
+	}
+
+	argumentTypes, ok := e.functions[funcName(cmd.Args[0])]
+	if !ok || len(cmd.Args)-1 < len(argumentTypes) {
+		return
+	}
+
+	line, column := e.lines.position(int(cmd.Position()))
+	s := TranslationString{Path: e.path, Line: line, Column: column}
+	for i, at := range argumentTypes {
+		if at == ArgTypeSkip {
+			continue
+		}
+
+		str, ok := cmd.Args[i+1].(*parse.StringNode)
+		if !ok {
+			return
+		}
+
+		switch at {
+		case ArgTypeSingular:
+			s.Singular = str.Text
+		case ArgTypePlural:
+			s.Plural = str.Text
+		case ArgTypeContext:
+			s.Context = str.Text
+		case ArgTypeDomain:
+			s.Domain = str.Text
+		}
+	}
+
+	s.Flags = format.GoFlags(s.Singular, s.Plural)
+	e.strs = append(e.strs, s)
+}
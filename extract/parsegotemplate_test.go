@@ -0,0 +1,76 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGoTemplate(t *testing.T) {
+	cwd, _ := os.Getwd()
+	path := filepath.Join(cwd, "testdata", "gotemplates", "index.tmpl")
+
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+
+	tr, err := ParseKeyword("tr")
+	require.Nil(t, err)
+	pkgKeyword, err := ParseKeyword("github.com/acme/i18n.T2")
+	require.Nil(t, err)
+
+	strs, err := GoTemplateExtractor{Keywords: append([]Keyword{tr, pkgKeyword}, DefaultKeywords...)}.Extract(path, content)
+	require.Nil(t, err)
+
+	messages := map[string]TranslationString{}
+	for _, msg := range strs {
+		messages[msg.Context+":"+msg.Singular] = msg
+	}
+
+	require.Len(t, messages, 6)
+	require.Contains(t, messages, ":String from gettext")
+	require.Equal(t, []string{"go-format"}, messages[":Hello %s"].Flags)
+	require.Equal(t, "%d messages", messages[":One message"].Plural)
+	require.Contains(t, messages, "login:Sign in")
+	require.Contains(t, messages, ":String in range")
+	require.Contains(t, messages, ":From keyword")
+
+	// Methods are not template functions, even if they have the name of one
+	require.NotContains(t, messages, ":page")
+	require.NotContains(t, messages, ":Method call")
+
+	require.Equal(t, 1, messages[":String from gettext"].Line)
+	require.Equal(t, 4, messages[":Hello %s"].Line)
+	require.Equal(t, 9, messages[":String in range"].Line)
+}
+
+func TestExtractorMapping(t *testing.T) {
+	mapping, err := ExtractorMapping(DefaultExtractors(), map[string][]string{"django": {".html", ".txt"}}, []string{".tmpl=django", ".vue=javascript"})
+	require.Nil(t, err)
+
+	require.Equal(t, DjangoExtractor{}, mapping[".html"])
+	require.Equal(t, DjangoExtractor{}, mapping[".txt"])
+	require.Equal(t, DjangoExtractor{}, mapping[".tmpl"])
+	require.Equal(t, GoTemplateExtractor{}, mapping[".gotmpl"])
+	require.Equal(t, JavaScriptExtractor{}, mapping[".vue"])
+	require.Equal(t, JavaScriptExtractor{}, mapping[".js"])
+
+	_, err = ExtractorMapping(DefaultExtractors(), nil, []string{".md=markdown"})
+	require.NotNil(t, err)
+
+	_, err = ExtractorMapping(DefaultExtractors(), nil, []string{"html"})
+	require.NotNil(t, err)
+}
+
+func TestLineCounter(t *testing.T) {
+	lines := &lineCounter{content: "ab\ncd\n\nef"}
+
+	for _, c := range []struct{ offset, line, column int }{
+		{0, 1, 1}, {1, 1, 2}, {3, 2, 1}, {8, 4, 2}, {4, 2, 2}, {6, 3, 1}, {9, 4, 3},
+	} {
+		line, column := lines.position(c.offset)
+		require.Equal(t, c.line, line, "line of offset %d", c.offset)
+		require.Equal(t, c.column, column, "column of offset %d", c.offset)
+	}
+}
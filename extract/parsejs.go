@@ -0,0 +1,533 @@
+package extract
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yzzyx/makemessage/internal/format"
+)
+
+// jsFunctions lists the translation functions of Django's JavaScript catalog
+var jsFunctions = map[string][]ArgType{
+	"gettext":   {ArgTypeSingular},
+	"ngettext":  {ArgTypeSingular, ArgTypePlural},
+	"pgettext":  {ArgTypeContext, ArgTypeSingular},
+	"npgettext": {ArgTypeContext, ArgTypeSingular, ArgTypePlural},
+}
+
+type jsTokenType int
+
+const (
+	jsTokenIdent jsTokenType = iota
+	jsTokenString
+	jsTokenTemplate // Template literal containing interpolations
+	jsTokenPunct
+)
+
+type jsToken struct {
+	typ    jsTokenType
+	value  string
+	line   int
+	column int
+}
+
+type jsxKind int
+
+const (
+	jsxTag        jsxKind = iota // Inside an opening or closing tag, e.g. <p class="a">
+	jsxChildren                  // Text and elements between an opening and a closing tag
+	jsxExpression                // Javascript expression inside braces
+)
+
+// jsxState is an element of the stack of nested JSX tags and expressions being tokenized
+type jsxState struct {
+	kind    jsxKind
+	closing bool // Tag is a closing tag, e.g. </p>
+	tokens  int  // Number of tokens read in a tag
+	depth   int  // Number of open braces in an expression
+}
+
+// jsTokenizer splits javascript or typescript code into the tokens needed to find translation calls.
+// Comments, regular expression literals and JSX text are skipped, and string literals are decoded.
+type jsTokenizer struct {
+	content   string
+	pos       int
+	line      int
+	lineStart int      // Offset of the start of the current line, used to calculate columns
+	last      *jsToken // Last token returned, used to tell regular expressions from divisions
+
+	jsx       bool       // Code may contain JSX elements
+	jsxStack  []jsxState // Nested JSX tags and expressions at the current position
+	parens    []bool     // For each open parenthesis, if it starts the condition of a statement
+	condition bool       // Last token closed the condition of a statement, e.g. if (a)
+}
+
+func (t *jsTokenizer) peek(offset int) byte {
+	if t.pos+offset < len(t.content) {
+		return t.content[t.pos+offset]
+	}
+	return 0
+}
+
+// newline moves to the line following the newline at offset
+func (t *jsTokenizer) newline(offset int) {
+	t.line++
+	t.lineStart = offset + 1
+}
+
+// column returns the column of the current position, starting at 1
+func (t *jsTokenizer) column() int {
+	return t.pos - t.lineStart + 1
+}
+
+// skipSpace skips whitespace and comments
+func (t *jsTokenizer) skipSpace() {
+	for t.pos < len(t.content) {
+		c := t.content[t.pos]
+		switch {
+		case c == '\n':
+			t.newline(t.pos)
+			t.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			t.pos++
+		case c == '/' && t.peek(1) == '/':
+			end := strings.IndexByte(t.content[t.pos:], '\n')
+			if end == -1 {
+				t.pos = len(t.content)
+				return
+			}
+			t.pos += end
+		case c == '/' && t.peek(1) == '*':
+			end := strings.Index(t.content[t.pos+2:], "*/")
+			if end == -1 {
+				end = len(t.content) - t.pos - 2
+			}
+			comment := t.content[t.pos : t.pos+2+end]
+			if n := strings.Count(comment, "\n"); n > 0 {
+				t.line += n - 1
+				t.newline(t.pos + strings.LastIndexByte(comment, '\n'))
+			}
+			t.pos += end + 4
+		default:
+			return
+		}
+	}
+}
+
+// regexAllowed checks if a '/' at the current position starts a regular expression.
+// This is also where a '<' starts a JSX element.
+func (t *jsTokenizer) regexAllowed() bool {
+	if state := t.jsxState(); state != nil && state.kind == jsxTag {
+		return false
+	}
+	if t.last == nil {
+		return true
+	}
+	switch t.last.typ {
+	case jsTokenIdent:
+		switch t.last.value {
+		case "return", "typeof", "instanceof", "in", "of", "new", "delete", "void", "throw", "case", "do", "else", "yield", "await":
+			return true
+		}
+		return false
+	case jsTokenPunct:
+		if t.last.value == ")" {
+			return t.condition
+		}
+		return t.last.value != "]" && t.last.value != "}"
+	default:
+		return false
+	}
+}
+
+// skipRegex skips a regular expression literal, including its flags
+func (t *jsTokenizer) skipRegex() {
+	inClass := false
+	for t.pos++; t.pos < len(t.content); t.pos++ {
+		c := t.content[t.pos]
+		switch {
+		case c == '\\':
+			t.pos++
+		case c == '\n':
+			// Not a valid regular expression, so it's probably a division after all
+			return
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			t.pos++
+			for t.pos < len(t.content) && isJSIdentChar(t.content[t.pos]) {
+				t.pos++
+			}
+			return
+		}
+	}
+}
+
+// readString reads a quoted string or template literal, and decodes its escape sequences
+func (t *jsTokenizer) readString() (jsToken, error) {
+	quote := t.content[t.pos]
+	token := jsToken{typ: jsTokenString, line: t.line, column: t.column()}
+	var sb strings.Builder
+
+	for t.pos++; t.pos < len(t.content); t.pos++ {
+		c := t.content[t.pos]
+		switch {
+		case c == quote:
+			t.pos++
+			token.value = sb.String()
+			return token, nil
+		case c == '\n':
+			if quote != '`' {
+				return token, fmt.Errorf("unterminated string on line %d", token.line)
+			}
+			t.newline(t.pos)
+			sb.WriteByte(c)
+		case c == '$' && quote == '`' && t.peek(1) == '{':
+			token.typ = jsTokenTemplate
+			sb.WriteByte(c)
+		case c == '\\':
+			t.pos++
+			if t.pos >= len(t.content) {
+				break
+			}
+			t.pos += t.decodeEscape(&sb) - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return token, fmt.Errorf("unterminated string on line %d", token.line)
+}
+
+// decodeEscape decodes the escape sequence at the current position (after the backslash),
+// and returns the number of bytes consumed
+func (t *jsTokenizer) decodeEscape(sb *strings.Builder) int {
+	c := t.content[t.pos]
+	switch c {
+	case 'n':
+		sb.WriteByte('\n')
+	case 't':
+		sb.WriteByte('\t')
+	case 'r':
+		sb.WriteByte('\r')
+	case 'b':
+		sb.WriteByte('\b')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'v':
+		sb.WriteByte('\v')
+	case '0':
+		sb.WriteByte(0)
+	case '\n':
+		// Line continuation
+		t.newline(t.pos)
+	case 'x', 'u':
+		var digits string
+		var consumed int
+		if c == 'u' && t.peek(1) == '{' {
+			end := strings.IndexByte(t.content[t.pos+2:], '}')
+			if end == -1 {
+				sb.WriteByte(c)
+				return 1
+			}
+			digits = t.content[t.pos+2 : t.pos+2+end]
+			consumed = end + 3
+		} else {
+			n := 2
+			if c == 'u' {
+				n = 4
+			}
+			if t.pos+1+n > len(t.content) {
+				sb.WriteByte(c)
+				return 1
+			}
+			digits = t.content[t.pos+1 : t.pos+1+n]
+			consumed = n + 1
+		}
+
+		r, err := strconv.ParseUint(digits, 16, 32)
+		if err != nil {
+			sb.WriteByte(c)
+			return 1
+		}
+		sb.WriteRune(rune(r))
+		return consumed
+	default:
+		sb.WriteByte(c)
+	}
+	return 1
+}
+
+func isJSIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// jsxState returns the innermost JSX tag or expression at the current position, if any
+func (t *jsTokenizer) jsxState() *jsxState {
+	if len(t.jsxStack) == 0 {
+		return nil
+	}
+	return &t.jsxStack[len(t.jsxStack)-1]
+}
+
+// skipJSXText skips the text between JSX tags, up to the next tag or expression.
+// Quotes are ordinary characters in JSX text, e.g. <p>Don't</p>.
+func (t *jsTokenizer) skipJSXText() {
+	for ; t.pos < len(t.content); t.pos++ {
+		switch t.content[t.pos] {
+		case '<', '{':
+			return
+		case '\n':
+			t.newline(t.pos)
+		}
+	}
+}
+
+// startsJSX checks if a '<' at the current position starts a JSX element
+func (t *jsTokenizer) startsJSX() bool {
+	if !t.jsx || !t.regexAllowed() {
+		return false
+	}
+	c := t.peek(1)
+	return c == '>' || c == '_' || c == '$' || unicode.IsLetter(rune(c))
+}
+
+// updateState keeps track of statement conditions and nested JSX elements, using the token just read.
+// jsxStart is set if the token is a '<' that starts a JSX element.
+func (t *jsTokenizer) updateState(token *jsToken, jsxStart bool) {
+	t.condition = false
+	if token.typ == jsTokenPunct {
+		switch token.value {
+		case "(":
+			t.parens = append(t.parens, t.last != nil && t.last.typ == jsTokenIdent && isStatementKeyword(t.last.value))
+		case ")":
+			if n := len(t.parens); n > 0 {
+				t.condition = t.parens[n-1]
+				t.parens = t.parens[:n-1]
+			}
+		}
+	}
+
+	state := t.jsxState()
+	if state == nil {
+		if jsxStart {
+			t.jsxStack = append(t.jsxStack, jsxState{kind: jsxTag})
+		}
+		return
+	}
+
+	switch state.kind {
+	case jsxChildren:
+		switch token.value {
+		case "<":
+			t.skipSpace()
+			t.jsxStack = append(t.jsxStack, jsxState{kind: jsxTag, closing: t.peek(0) == '/'})
+		case "{":
+			t.jsxStack = append(t.jsxStack, jsxState{kind: jsxExpression, depth: 1})
+		}
+	case jsxTag:
+		state.tokens++
+		switch {
+		case state.tokens == 2 && !state.closing && (token.value == "," || token.value == "extends"):
+			// Not an element but type parameters in typescript, e.g. <T,>(a: T) => a
+			t.jsxStack = t.jsxStack[:len(t.jsxStack)-1]
+		case token.value == "{":
+			t.jsxStack = append(t.jsxStack, jsxState{kind: jsxExpression, depth: 1})
+		case token.value == ">":
+			t.jsxStack = t.jsxStack[:len(t.jsxStack)-1]
+			if state.closing {
+				if parent := t.jsxState(); parent != nil && parent.kind == jsxChildren {
+					t.jsxStack = t.jsxStack[:len(t.jsxStack)-1]
+				}
+			} else if t.last == nil || t.last.value != "/" {
+				t.jsxStack = append(t.jsxStack, jsxState{kind: jsxChildren})
+			}
+		}
+	case jsxExpression:
+		switch {
+		case jsxStart:
+			t.jsxStack = append(t.jsxStack, jsxState{kind: jsxTag})
+		case token.value == "{":
+			state.depth++
+		case token.value == "}":
+			state.depth--
+			if state.depth == 0 {
+				t.jsxStack = t.jsxStack[:len(t.jsxStack)-1]
+			}
+		}
+	}
+}
+
+// isStatementKeyword checks if name is a keyword followed by a condition in parentheses
+func isStatementKeyword(name string) bool {
+	switch name {
+	case "if", "while", "for", "with":
+		return true
+	}
+	return false
+}
+
+// next returns the next token, or nil at the end of the content
+func (t *jsTokenizer) next() (*jsToken, error) {
+	for {
+		if state := t.jsxState(); state != nil && state.kind == jsxChildren {
+			t.skipJSXText()
+		} else {
+			t.skipSpace()
+		}
+		if t.pos >= len(t.content) {
+			return nil, nil
+		}
+
+		c := t.content[t.pos]
+		jsxStart := c == '<' && t.startsJSX()
+		var token jsToken
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			var err error
+			token, err = t.readString()
+			if err != nil {
+				return nil, err
+			}
+		case c == '/' && t.regexAllowed():
+			t.skipRegex()
+			t.last = &jsToken{typ: jsTokenString}
+			continue
+		case isJSIdentChar(c):
+			start, column := t.pos, t.column()
+			for t.pos < len(t.content) && isJSIdentChar(t.content[t.pos]) {
+				t.pos++
+			}
+			token = jsToken{typ: jsTokenIdent, value: t.content[start:t.pos], line: t.line, column: column}
+		default:
+			_, size := utf8.DecodeRuneInString(t.content[t.pos:])
+			token = jsToken{typ: jsTokenPunct, value: t.content[t.pos : t.pos+size], line: t.line, column: t.column()}
+			t.pos += size
+		}
+
+		t.updateState(&token, jsxStart)
+		t.last = &token
+		return &token, nil
+	}
+}
+
+// ScriptError is returned when javascript or typescript code cannot be tokenized
+type ScriptError struct {
+	Path string
+	Err  error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("could not parse %s: %v", e.Path, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// extractJS finds all calls to translation functions in javascript or typescript code.
+// The content is assumed to start at firstLine and firstColumn of the file.
+func extractJS(path string, firstLine, firstColumn int, content string) ([]TranslationString, error) {
+	t := &jsTokenizer{content: content, line: firstLine, lineStart: 1 - firstColumn}
+
+	// In typescript, <T>a is a type assertion rather than an element
+	switch filepath.Ext(path) {
+	case ".ts", ".mts", ".cts":
+	default:
+		t.jsx = true
+	}
+
+	var tokens []*jsToken
+	for {
+		token, err := t.next()
+		if err != nil {
+			return nil, &ScriptError{Path: path, Err: err}
+		}
+		if token == nil {
+			break
+		}
+		tokens = append(tokens, token)
+	}
+
+	var strs []TranslationString
+	for i := 0; i < len(tokens)-1; i++ {
+		argumentTypes, ok := jsFunctions[tokens[i].value]
+		if !ok || tokens[i].typ != jsTokenIdent || tokens[i+1].value != "(" {
+			continue
+		}
+
+		// Function declarations, e.g. "function gettext(msgid)"
+		if i > 0 && tokens[i-1].value == "function" {
+			continue
+		}
+
+		s := TranslationString{Path: path, Line: tokens[i].line, Column: tokens[i].column}
+		pos := i + 2
+
+		complete := true
+		for argIdx, at := range argumentTypes {
+			if argIdx > 0 {
+				if pos >= len(tokens) || tokens[pos].value != "," {
+					complete = false
+					break
+				}
+				pos++
+			}
+
+			// Strings may be concatenated, e.g. gettext("a " + "b")
+			var value string
+			for {
+				if pos >= len(tokens) || tokens[pos].typ != jsTokenString {
+					complete = false
+					break
+				}
+				value += tokens[pos].value
+				pos++
+
+				if pos+1 < len(tokens) && tokens[pos].value == "+" && tokens[pos+1].typ == jsTokenString {
+					pos++
+					continue
+				}
+				break
+			}
+
+			if !complete {
+				break
+			}
+
+			switch at {
+			case ArgTypeSingular:
+				s.Singular = value
+			case ArgTypePlural:
+				s.Plural = value
+			case ArgTypeContext:
+				s.Context = value
+			}
+		}
+
+		if !complete {
+			continue
+		}
+
+		s.Flags = format.JavaScriptFlags(s.Singular, s.Plural)
+		strs = append(strs, s)
+		i = pos - 1
+	}
+	return strs, nil
+}
+
+// JavaScriptExtractor extracts strings from javascript and typescript code
+type JavaScriptExtractor struct{}
+
+func (JavaScriptExtractor) Extensions() []string {
+	return []string{".js", ".mjs", ".jsx", ".ts", ".tsx"}
+}
+
+func (JavaScriptExtractor) Extract(path string, content []byte) ([]TranslationString, error) {
+	return extractJS(path, 1, 1, string(content))
+}
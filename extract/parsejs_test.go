@@ -0,0 +1,104 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJS(t *testing.T) {
+	cwd, _ := os.Getwd()
+	basePath := filepath.Join(cwd, "testdata", "static")
+
+	messages := map[string]TranslationString{}
+	for _, name := range []string{"app.js", "app.ts"} {
+		content, err := os.ReadFile(filepath.Join(basePath, name))
+		require.Nil(t, err)
+
+		strs, err := JavaScriptExtractor{}.Extract(filepath.Join(basePath, name), content)
+		require.Nil(t, err)
+
+		for _, msg := range strs {
+			messages[msg.Context+":"+msg.Singular] = msg
+		}
+	}
+
+	require.Len(t, messages, 9)
+	require.Equal(t, filepath.Join(basePath, "app.js")+":10:18", messages[":String from gettext"].Reference(true))
+	require.Equal(t, "Many items", messages[":One item"].Plural)
+	require.Contains(t, messages, "month name:May")
+	require.Equal(t, "parties", messages["group:party"].Plural)
+	require.Equal(t, filepath.Join(basePath, "app.js")+":13", messages["group:party"].Reference(false))
+	require.Contains(t, messages, ":String from concatenation")
+	require.Contains(t, messages, ":String from template literal")
+	require.Contains(t, messages, ":Escaped \"quotes\" and å")
+	require.Equal(t, []string{"javascript-format"}, messages[":Hello %(name)s"].Flags)
+	require.Contains(t, messages, ":String from typescript")
+}
+
+func TestParseJSTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "apostrophe in JSX text",
+			path:    "app.jsx",
+			content: "const A = () => <p>Don't do it</p>;\ngettext('After');",
+			want:    []string{"After"},
+		},
+		{
+			name:    "nested JSX elements and expressions",
+			path:    "app.jsx",
+			content: "const A = () => <div title=\"it's\">It's <b>{gettext('Bold')}</b> {x && <i>isn't</i>}<br/></div>;\ngettext('After');",
+			want:    []string{"Bold", "After"},
+		},
+		{
+			name:    "type parameters in tsx",
+			path:    "app.tsx",
+			content: "const f = <T,>(a: T) => a;\ngettext('After');",
+			want:    []string{"After"},
+		},
+		{
+			name:    "type assertion in typescript",
+			path:    "app.ts",
+			content: "const a = <string>b; gettext('After');",
+			want:    []string{"After"},
+		},
+		{
+			name:    "comparison",
+			path:    "app.js",
+			content: "if (a <b) { gettext('After'); }",
+			want:    []string{"After"},
+		},
+		{
+			name:    "regular expression after condition",
+			path:    "app.js",
+			content: "if (a) /'/.test(s);\ngettext('After');",
+			want:    []string{"After"},
+		},
+		{
+			name:    "division after parentheses",
+			path:    "app.js",
+			content: "x = (a) / 2 + gettext('After') / 3;",
+			want:    []string{"After"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strs, err := JavaScriptExtractor{}.Extract(tt.path, []byte(tt.content))
+			require.Nil(t, err)
+
+			var got []string
+			for _, s := range strs {
+				got = append(got, s.Singular)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
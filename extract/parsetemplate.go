@@ -0,0 +1,281 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/yzzyx/makemessage/internal/format"
+)
+
+// DjangoExtractor extracts strings from django templates, using the trans and blocktrans tags
+type DjangoExtractor struct{}
+
+func (DjangoExtractor) Extensions() []string {
+	return []string{".html"}
+}
+
+func (DjangoExtractor) Extract(path string, content []byte) ([]TranslationString, error) {
+	return parseTemplate(path, string(content))
+}
+
+// parseTemplate returns the strings in the django template content. The domain of a string can be given
+// in its tag, as in {% trans "Users" domain "admin" %}, or for the whole file when loading the i18n
+// library, as in {% load i18n domain="admin" %}.
+func parseTemplate(path string, content string) ([]TranslationString, error) {
+	var strs []TranslationString
+	var warnings Warnings
+	var fileDomain string
+	lines := &lineCounter{content: content}
+
+	for pos := 0; pos < len(content); pos++ {
+		// Extract strings from javascript in script blocks as well
+		if pos+7 <= len(content) && strings.EqualFold(content[pos:pos+7], "<script") {
+			start := strings.IndexByte(content[pos:], '>')
+			end := strings.Index(strings.ToLower(content[pos:]), "</script")
+			if start != -1 && end > start && isJavaScriptTag(content[pos:pos+start]) {
+				line, column := lines.position(pos + start + 1)
+				jsStrs, err := extractJS(path, line, column, content[pos+start+1:pos+end])
+				if err != nil {
+					// Keep the strings found in the rest of the template
+					warnings = append(warnings, fmt.Sprintf("%s:%d: skipping script block: %v", path, line, errors.Unwrap(err)))
+				}
+				strs = append(strs, jsStrs...)
+			}
+		}
+
+		if strings.HasPrefix(content[pos:], "{%") {
+			line, column := lines.position(pos)
+			pos += 2
+			for unicode.IsSpace(rune(content[pos])) {
+				pos++
+			}
+
+			if strings.HasPrefix(content[pos:], "load ") {
+				domain, err := loadDomain(path, line, content[pos:])
+				if err != nil {
+					return nil, err
+				}
+				if domain != "" {
+					fileDomain = domain
+				}
+			} else if strings.HasPrefix(content[pos:], "trans ") {
+				newpos, err := handleTransTag(&strs, path, line, column, content[pos:])
+				if err != nil {
+					return nil, err
+				}
+				pos += newpos
+			} else if strings.HasPrefix(content[pos:], "blocktrans") {
+				newpos, err := handleBlockTransTag(&strs, path, line, column, content[pos:])
+				if err != nil {
+					return nil, err
+				}
+				pos += newpos
+			}
+		}
+	}
+
+	for i := range strs {
+		if strs[i].Domain == "" {
+			strs[i].Domain = fileDomain
+		}
+	}
+	if len(warnings) > 0 {
+		return strs, warnings
+	}
+	return strs, nil
+}
+
+// scriptTypeRegexp matches the type attribute of a script tag
+var scriptTypeRegexp = regexp.MustCompile(`(?i)\stype\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// javaScriptTypes are the types of script tags containing javascript, in lower case
+var javaScriptTypes = map[string]bool{
+	"":                         true,
+	"module":                   true,
+	"text/javascript":          true,
+	"application/javascript":   true,
+	"application/x-javascript": true,
+	"text/ecmascript":          true,
+	"application/ecmascript":   true,
+}
+
+// isJavaScriptTag checks if the opening script tag, without the closing '>', has no type or a javascript type,
+// so that e.g. "text/x-template" and "application/ld+json" scripts are not parsed as javascript
+func isJavaScriptTag(tag string) bool {
+	if len(tag) > 7 && !unicode.IsSpace(rune(tag[7])) && tag[7] != '/' {
+		return false // Another tag, such as <scripts>
+	}
+
+	m := scriptTypeRegexp.FindStringSubmatch(tag)
+	if m == nil {
+		return true
+	}
+	scriptType, _, _ := strings.Cut(m[1]+m[2]+m[3], ";")
+	return javaScriptTypes[strings.ToLower(strings.TrimSpace(scriptType))]
+}
+
+// tagToken is a part of the contents of a template tag, either a quoted string or a word outside quotes
+type tagToken struct {
+	text   string // Without the quotes
+	quoted bool
+}
+
+// tagTokens splits the contents of a template tag into quoted strings and the words between them,
+// so that keywords such as "context" are only looked for outside strings
+func tagTokens(path string, line int, tag string) ([]tagToken, error) {
+	var tokens []tagToken
+	for pos := 0; pos < len(tag); {
+		switch c := tag[pos]; {
+		case unicode.IsSpace(rune(c)):
+			pos++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(tag[pos+1:], c)
+			if end == -1 {
+				return nil, fmt.Errorf("could not find end of string in template %s:%d", path, line)
+			}
+			tokens = append(tokens, tagToken{text: tag[pos+1 : pos+1+end], quoted: true})
+			pos += end + 2
+		default:
+			end := strings.IndexFunc(tag[pos:], func(r rune) bool {
+				return unicode.IsSpace(r) || r == '"' || r == '\''
+			})
+			if end == -1 {
+				end = len(tag) - pos
+			}
+			tokens = append(tokens, tagToken{text: tag[pos : pos+end]})
+			pos += end
+		}
+	}
+	return tokens, nil
+}
+
+// stringArgument returns the quoted string following the first word name outside quotes in tokens,
+// e.g. "admin" for name "domain" in `trans "Users" domain "admin"`. An empty string is returned if
+// there is no such word, or if it isn't followed by a quoted string.
+func stringArgument(tokens []tagToken, name string) string {
+	for i, t := range tokens {
+		if !t.quoted && t.text == name {
+			if i+1 < len(tokens) && tokens[i+1].quoted {
+				return tokens[i+1].text
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// loadDomain returns the domain given in a load tag, as in {% load i18n domain="admin" %}
+func loadDomain(path string, line int, content string) (string, error) {
+	tagEndPos := strings.Index(content, "%}")
+	if tagEndPos == -1 {
+		return "", fmt.Errorf("could not find end of tag in template %s:%d", path, line)
+	}
+
+	tokens, err := tagTokens(path, line, content[5:tagEndPos]) // Skip "load "
+	if err != nil {
+		return "", err
+	}
+	return stringArgument(tokens, "domain="), nil
+}
+
+func handleTransTag(strs *[]TranslationString, path string, line, column int, content string) (int, error) {
+	tagEndPos := strings.Index(content, "%}")
+	if tagEndPos == -1 {
+		return 0, fmt.Errorf("could not find end of tag in template %s:%d", path, line)
+	}
+
+	tokens, err := tagTokens(path, line, content[5:tagEndPos]) // Skip "trans"
+	if err != nil {
+		return 0, err
+	}
+	tagEndPos += 2
+
+	// we're only interested in strings
+	if len(tokens) == 0 || !tokens[0].quoted {
+		return tagEndPos, nil
+	}
+	singular := tokens[0].text
+
+	*strs = append(*strs, TranslationString{
+		Path:     path,
+		Line:     line,
+		Column:   column,
+		Singular: singular,
+		Context:  stringArgument(tokens[1:], "context"),
+		Domain:   stringArgument(tokens[1:], "domain"),
+		Flags:    format.TemplateFlags(singular),
+	})
+	return tagEndPos, nil
+}
+
+func handleBlockTransTag(strs *[]TranslationString, path string, line, column int, content string) (int, error) {
+	var singular, plural string
+
+	tagEndPos := strings.Index(content, "%}")
+	if tagEndPos == -1 {
+		return 0, fmt.Errorf("could not find end of tag in template %s:%d", path, line)
+	}
+
+	tokens, err := tagTokens(path, line, content[10:tagEndPos]) // Skip "blocktrans"
+	if err != nil {
+		return 0, err
+	}
+	context := stringArgument(tokens, "context")
+	domain := stringArgument(tokens, "domain")
+	tagEndPos += 2
+
+	var pos int
+	hasPlural := false
+	for pos = tagEndPos; pos < len(content); pos++ {
+		if strings.HasPrefix(content[pos:], "{% plural ") {
+			singular = content[tagEndPos:pos]
+			hasPlural = true
+
+			pos += 2
+			tagEndPos = strings.Index(content[pos:], "%}")
+			if tagEndPos == -1 {
+				return 0, fmt.Errorf("could not find end of tag in template %s:%d", path, line)
+			}
+			tagEndPos += pos + 2
+		} else if strings.HasPrefix(content[pos:], "{% endblocktrans") {
+			if hasPlural {
+				plural = content[tagEndPos:pos]
+			} else {
+				singular = content[tagEndPos:pos]
+			}
+			break
+		}
+	}
+
+	replacer := strings.NewReplacer("{{ ", "{{", " }}", "}}")
+	for {
+		n := replacer.Replace(singular)
+		if n == singular {
+			break
+		}
+		singular = n
+	}
+
+	for {
+		n := replacer.Replace(plural)
+		if n == plural {
+			break
+		}
+		plural = n
+	}
+
+	*strs = append(*strs, TranslationString{
+		Path:     path,
+		Line:     line,
+		Column:   column,
+		Singular: singular,
+		Plural:   plural,
+		Context:  context,
+		Domain:   domain,
+		Flags:    format.TemplateFlags(singular, plural),
+	})
+	return tagEndPos, nil
+}
@@ -0,0 +1,171 @@
+package extract
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	cwd, _ := os.Getwd()
+	basePath := filepath.Join(cwd, "testdata")
+
+	var defaultDom []TranslationString
+	err := filepath.Walk(filepath.Join(basePath, "templates"), func(path string, info os.FileInfo, err error) error {
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		strs, err := DjangoExtractor{}.Extract(path, content)
+		defaultDom = append(defaultDom, strs...)
+		return err
+	})
+	require.Nil(t, err)
+
+	messageSingular := map[string]bool{}
+	messagePlural := map[string]bool{}
+	for _, msg := range defaultDom {
+		var prefix string
+		if msg.Context != "" {
+			prefix = msg.Context + ":"
+		}
+		messageSingular[prefix+msg.Singular] = true
+
+		if msg.Plural != "" {
+			messagePlural[prefix+msg.Plural] = true
+		}
+	}
+
+	expectedSingular := []string{
+		"String from trans",
+		"String from trans to var",
+		"testctx:String from trans with context",
+		"\nString from blocktrans\n",
+		"\nString from blocktrans with plural\n",
+		"String from script block",
+		"multiline:String from multi-line blocktrans",
+		"String after multi-line tag",
+	}
+
+	expectedPlural := []string{
+		"\nPlural for blocktrans\n",
+	}
+
+	for _, expected := range expectedSingular {
+		require.True(t, messageSingular[expected], "expected to find string '%s' in messages", expected)
+	}
+
+	for _, expected := range expectedPlural {
+		require.True(t, messagePlural[expected], "expected to find string '%s' in plural messages", expected)
+	}
+
+	flags := map[string][]string{}
+	references := map[string]string{}
+	for _, msg := range defaultDom {
+		flags[msg.Singular] = msg.Flags
+		references[msg.Singular] = strings.TrimPrefix(msg.Reference(true), basePath+"/")
+	}
+
+	require.Equal(t, "templates/index.html:1:1", references["String from trans"])
+	require.Equal(t, "templates/index.html:9:1", references["\nString from blocktrans with plural\n"])
+	require.Equal(t, "templates/index.html:14:1", references["Hello %(name)s"])
+	require.Equal(t, "templates/index.html:19:12", references["String from script block"])
+	require.Equal(t, "templates/index.html:21:1", references["String from multi-line blocktrans"])
+	require.Equal(t, "templates/index.html:23:1", references["String after multi-line tag"])
+
+	require.Empty(t, flags["String from trans"])
+	require.Equal(t, []string{"python-format"}, flags["Hello %(name)s"])
+	require.Empty(t, flags["Hello {{user.name}}"])
+}
+
+func TestParseTemplateScripts(t *testing.T) {
+	content := `<script type="text/x-template"><p>{{ gettext("Not javascript") }}</p></script>
+<script type="application/ld+json">{"description": "Don't parse this"}</script>
+<script type="module">gettext("Module");</script>
+<script>const s = 'Unterminated;</script>
+<script type="text/javascript">gettext("After broken script");</script>
+{% trans "After scripts" %}`
+
+	strs, err := parseTemplate("index.html", content)
+	require.Equal(t, Warnings{"index.html:4: skipping script block: unterminated string on line 4"}, err)
+
+	var singulars []string
+	for _, s := range strs {
+		singulars = append(singulars, s.Singular)
+	}
+	require.Equal(t, []string{"Module", "After broken script", "After scripts"}, singulars)
+	require.Equal(t, 5, strs[1].Line)
+
+	require.True(t, isJavaScriptTag(`<script`))
+	require.True(t, isJavaScriptTag(`<script src="app.js" TYPE='Text/JavaScript; charset=utf-8'`))
+	require.False(t, isJavaScriptTag(`<scripts`))
+	require.False(t, isJavaScriptTag(`<script type=text/template`))
+}
+
+func TestParseTemplateDomains(t *testing.T) {
+	content := `{% load static %}{% load i18n domain="admin" %}
+{% trans "Users" %}
+{% trans "Invoices" domain "billing" %}
+{% trans "Due" context "invoice" domain 'billing' %}
+{% blocktrans domain "billing" count n=items|length %}One invoice{% plural %}{{ n }} invoices{% endblocktrans %}
+{% blocktrans %}Settings{% endblocktrans %}
+{% trans "Address" as domain %}
+`
+
+	strs, err := parseTemplate("admin.html", content)
+	require.Nil(t, err)
+
+	domains := map[string]string{}
+	for _, s := range strs {
+		domains[s.Singular] = s.Domain
+	}
+	require.Equal(t, map[string]string{
+		"Users":       "admin",
+		"Invoices":    "billing",
+		"Due":         "billing",
+		"One invoice": "billing",
+		"Settings":    "admin",
+		"Address":     "admin",
+	}, domains)
+
+	strs, err = parseTemplate("index.html", `{% load i18n %}{% trans "Home" %}`)
+	require.Nil(t, err)
+	require.Equal(t, "", strs[0].Domain)
+
+	_, err = parseTemplate("broken.html", `{% trans "Home" domain "admin %}`)
+	require.NotNil(t, err)
+}
+
+func TestParseTemplateQuotedKeywords(t *testing.T) {
+	content := `{% load i18n %}{% load "domain=" "x" %}
+{% trans "x" context "domain name" %}
+{% trans "context y" %}
+{% trans "Due" context 'invoice "domain" x' domain "billing" %}
+{% blocktrans with a="context z" b='domain "w"' %}Hello{% endblocktrans %}
+`
+
+	strs, err := parseTemplate("index.html", content)
+	require.Nil(t, err)
+	require.Len(t, strs, 4)
+
+	require.Equal(t, "x", strs[0].Singular)
+	require.Equal(t, "domain name", strs[0].Context)
+	require.Equal(t, "", strs[0].Domain)
+
+	require.Equal(t, "context y", strs[1].Singular)
+	require.Equal(t, "", strs[1].Context)
+
+	require.Equal(t, `invoice "domain" x`, strs[2].Context)
+	require.Equal(t, "billing", strs[2].Domain)
+
+	require.Equal(t, "Hello", strs[3].Singular)
+	require.Equal(t, "", strs[3].Context)
+	require.Equal(t, "", strs[3].Domain)
+}
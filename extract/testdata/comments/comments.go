@@ -0,0 +1,22 @@
+// This file is used to test translator comments and duplicate strings
+package comments
+
+import "github.com/leonelquinteros/gotext"
+
+func x() {
+	// TRANSLATORS: Button on the login page
+	gotext.Get("Sign in")
+
+	// This comment is not for translators
+	gotext.Get("Sign out")
+
+	gotext.Get("Sign in") // TRANSLATORS: Link in the menu
+
+	/*
+		Some context for developers.
+		TRANSLATORS: Shown when the user
+		has new messages
+	*/
+	gotext.GetN("One message", "%d messages", 2)
+	gotext.GetN("One message", "%d new messages", 2)
+}
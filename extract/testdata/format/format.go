@@ -0,0 +1,15 @@
+// This file is used to test detection of format strings
+package format
+
+import "github.com/leonelquinteros/gotext"
+
+func x() {
+	gotext.Get("Hello %s, you have %d messages", "user", 3)
+	gotext.Get("Completed 100%%")
+	gotext.GetN("One file", "%d files", 2)
+
+	// xgettext:no-go-format
+	gotext.Get("Literal %s")
+
+	gotext.Get("Forced format") // xgettext:go-format
+}
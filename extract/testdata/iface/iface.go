@@ -0,0 +1,30 @@
+// This file is used to test calls through interfaces and type parameters
+package iface
+
+import "github.com/leonelquinteros/gotext"
+
+// Translator is implemented by *gotext.Locale
+type Translator interface {
+	Get(str string, vars ...interface{}) string
+}
+
+// Getter is not implemented by *gotext.Locale
+type Getter interface {
+	Get(key string) string
+}
+
+// Named is matched by being explicitly listed as a keyword type
+type Named interface {
+	Tr(str string) string
+}
+
+func x(t Translator, g Getter, n Named, l gotext.Translator) {
+	t.Get("String through interface")
+	g.Get("Not a translation")
+	n.Tr("String through named interface")
+	l.Get("String through gotext.Translator")
+}
+
+func generic[T Translator](t T) {
+	t.Get("String through type parameter")
+}
@@ -0,0 +1,17 @@
+// This file is used to test dot-imports, no-op markers and local keywords
+package markers
+
+import . "github.com/leonelquinteros/gotext"
+
+// N_ marks a string for translation without translating it
+func N_(s string) string { return s }
+
+// T is a local helper, registered as a keyword in the tests
+func T(s string) string { return Get(s) }
+
+var marked = N_("String from N_ marker")
+
+func x() {
+	Get("String from dot-import")
+	T("String from local keyword")
+}
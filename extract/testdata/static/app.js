@@ -0,0 +1,25 @@
+// gettext("Not from a comment")
+/* ngettext("Not", "from a block comment", 2) */
+function gettext(msgid) {
+	return msgid;
+}
+
+const re = /gettext\("not from a regex"\)/g;
+const half = 10 / 2, quarter = half / 2;
+
+document.title = gettext("String from gettext");
+alert(ngettext('One item', 'Many items', count));
+const label = pgettext("month name", "May");
+const text = npgettext(
+	"group",
+	"party",
+	"parties",
+	n
+);
+
+const joined = gettext("String from " +
+	"concatenation");
+const literal = gettext(`String from template literal`);
+const interpolated = gettext(`Not ${literal}`);
+const escaped = gettext("Escaped \"quotes\" and å");
+const formatted = interpolate(gettext("Hello %(name)s"), {name: "x"}, true);
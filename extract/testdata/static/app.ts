@@ -0,0 +1 @@
+const greet = (name: string): string => gettext("String from typescript");
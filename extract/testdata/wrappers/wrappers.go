@@ -0,0 +1,33 @@
+// This file is used to test detection of wrappers around gotext functions
+package wrappers
+
+import "github.com/leonelquinteros/gotext"
+
+type Request struct {
+	locale *gotext.Locale
+}
+
+func (r *Request) T(s string, args ...interface{}) string {
+	return r.locale.Get(s, args...)
+}
+
+func (r *Request) TN(singular, plural string, n int) string {
+	return r.locale.GetN(singular, plural, n)
+}
+
+// TC wraps another wrapper, with the context first
+func (r *Request) TC(ctx, s string) string {
+	return r.T(gotext.NewLocale("", "").GetC(s, ctx))
+}
+
+func translate(s string) string {
+	r := &Request{}
+	return r.T(s)
+}
+
+func x(r *Request) {
+	r.T("String from wrapper")
+	r.TN("Singular from wrapper", "Plural from wrapper", 2)
+	r.TC("wrapctx", "String from wrapper with context")
+	translate("String from wrapped wrapper")
+}
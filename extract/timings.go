@@ -0,0 +1,62 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Timings records how long each step of extracting strings and updating message files takes.
+// A nil *Timings is valid, and doesn't record anything. It's safe for concurrent use.
+type Timings struct {
+	mu        sync.Mutex
+	steps     []string // In the order they were first recorded
+	durations map[string]time.Duration
+}
+
+// Add adds d to the time spent on step
+func (t *Timings) Add(step string, d time.Duration) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.durations == nil {
+		t.durations = map[string]time.Duration{}
+	}
+	if _, ok := t.durations[step]; !ok {
+		t.steps = append(t.steps, step)
+	}
+	t.durations[step] += d
+}
+
+// Since adds the time passed since start to the time spent on step, as in "defer t.Since(step, time.Now())"
+func (t *Timings) Since(step string, start time.Time) {
+	t.Add(step, time.Since(start))
+}
+
+// Write writes the time spent on each step to w, in the order the steps were first recorded
+func (t *Timings) Write(w io.Writer) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	width := 0
+	for _, step := range t.steps {
+		width = max(width, len(step))
+	}
+
+	for _, step := range t.steps {
+		_, err := fmt.Fprintf(w, "%-*s %8.3fs\n", width, step, t.durations[step].Seconds())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package extract
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimings(t *testing.T) {
+	timings := &Timings{}
+	timings.Add("load go packages", 1500*time.Millisecond)
+	timings.Add("parse templates", 250*time.Millisecond)
+	timings.Add("load go packages", 500*time.Millisecond)
+
+	var buf bytes.Buffer
+	require.Nil(t, timings.Write(&buf))
+	require.Equal(t, "load go packages    2.000s\nparse templates     0.250s\n", buf.String())
+
+	// A nil *Timings doesn't record anything
+	var noTimings *Timings
+	noTimings.Add("load go packages", time.Second)
+	require.Nil(t, noTimings.Write(&buf))
+}
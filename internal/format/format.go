@@ -0,0 +1,181 @@
+// Package format detects placeholders in translation strings, and the
+// format flags ("#, go-format") that describe them in PO files.
+package format
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	// goVerbRegexp matches a single fmt verb, including flags, argument index, width and precision
+	goVerbRegexp = regexp.MustCompile(`%[-+# 0]*(\[\d+\])?(\d+|\*)?(\.(\d+|\*)?)?(\[\d+\])?[vTtbcdoOqxXUeEfFgGsp]`)
+
+	// pythonFormatRegexp matches named python placeholders, e.g. "%(name)s"
+	pythonFormatRegexp = regexp.MustCompile(`%\([^)]+\)[-+# 0]*\d*(\.\d+)?[diouxXeEfFgGcrsa]`)
+
+	// templateVarRegexp matches template variables, e.g. "{{name}}" or "{{ user.name }}"
+	templateVarRegexp = regexp.MustCompile(`\{\{\s*[\w.]+\s*\}\}`)
+
+	// overrideRegexp matches format overrides in comments, e.g. "xgettext:no-go-format"
+	overrideRegexp = regexp.MustCompile(`xgettext:\s*((?:no-)?[\w-]+-format)`)
+
+	// htmlTagRegexp matches opening and closing HTML tags
+	htmlTagRegexp = regexp.MustCompile(`<(/?)([a-zA-Z][\w-]*)[^<>]*>`)
+
+	// placeholderRegexp matches anything that must be kept as is when a string is rewritten:
+	// escaped percent signs, placeholders, template variables, HTML tags and HTML entities
+	placeholderRegexp = regexp.MustCompile(`%%|` + pythonFormatRegexp.String() + `|` + goVerbRegexp.String() + `|` +
+		templateVarRegexp.String() + `|` + htmlTagRegexp.String() + `|&(#\d+|#x[0-9a-fA-F]+|\w+);`)
+)
+
+// stripPercent removes escaped percent signs, so that they're not mistaken for verbs
+func stripPercent(s string) string {
+	return strings.ReplaceAll(s, "%%", "")
+}
+
+// goVerbMatches returns the submatch indexes of the fmt verbs in s, which must not contain escaped percent signs.
+// A verb with the space flag that is directly followed by a letter, such as "% o" in "10% off", is taken to be
+// plain text.
+func goVerbMatches(s string) [][]int {
+	var matches [][]int
+	for _, m := range goVerbRegexp.FindAllStringSubmatchIndex(s, -1) {
+		if strings.Contains(s[m[0]:m[1]], " ") && m[1] < len(s) && unicode.IsLetter(rune(s[m[1]])) {
+			continue
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// HasGoVerbs checks if s contains any fmt verbs
+func HasGoVerbs(s string) bool {
+	return len(goVerbMatches(stripPercent(s))) > 0
+}
+
+// GoFlags returns the format flags for a string extracted from go code
+func GoFlags(strs ...string) []string {
+	for _, s := range strs {
+		if HasGoVerbs(s) {
+			return []string{"go-format"}
+		}
+	}
+	return nil
+}
+
+// TemplateFlags returns the format flags for a string extracted from a template. Template variables
+// such as "{{name}}" are not flagged as python-brace-format, as "{{" is an escaped brace in that format.
+func TemplateFlags(strs ...string) []string {
+	for _, s := range strs {
+		if pythonFormatRegexp.MatchString(stripPercent(s)) {
+			return []string{"python-format"}
+		}
+	}
+	return nil
+}
+
+// JavaScriptFlags returns the format flags for a string extracted from javascript,
+// which is formatted with Django's interpolate() function
+func JavaScriptFlags(strs ...string) []string {
+	for _, s := range strs {
+		s = stripPercent(s)
+		if pythonFormatRegexp.MatchString(s) || len(goVerbMatches(s)) > 0 {
+			return []string{"javascript-format"}
+		}
+	}
+	return nil
+}
+
+// Overrides returns all format overrides (e.g. "no-go-format") found in comment
+func Overrides(comment string) []string {
+	var overrides []string
+	for _, m := range overrideRegexp.FindAllStringSubmatch(comment, -1) {
+		overrides = append(overrides, m[1])
+	}
+	return overrides
+}
+
+// ApplyOverrides replaces the flags in flags with the ones given in overrides,
+// so that e.g. "no-go-format" replaces "go-format"
+func ApplyOverrides(flags []string, overrides []string) []string {
+	for _, override := range overrides {
+		format := strings.TrimPrefix(override, "no-")
+
+		n := 0
+		for _, flag := range flags {
+			if flag != format && flag != "no-"+format {
+				flags[n] = flag
+				n++
+			}
+		}
+		flags = append(flags[:n], override)
+	}
+	return flags
+}
+
+// GoVerbs returns the fmt verbs in s, without flags, width and precision.
+// If explicit argument indexes are used, the order of the verbs doesn't matter and they are returned sorted.
+func GoVerbs(s string) []string {
+	var verbs []string
+	indexed := false
+	s = stripPercent(s)
+	for _, m := range goVerbMatches(s) {
+		verb := s[m[1]-1 : m[1]]
+		if idx := submatch(s, m, 1) + submatch(s, m, 5); idx != "" {
+			indexed = true
+			verb = idx + verb
+		}
+		verbs = append(verbs, "%"+verb)
+	}
+
+	if indexed {
+		sort.Strings(verbs)
+	}
+	return verbs
+}
+
+// PythonPlaceholders returns the sorted named python placeholders in s, e.g. "%(name)s"
+func PythonPlaceholders(s string) []string {
+	return sortedMatches(pythonFormatRegexp, stripPercent(s))
+}
+
+// TemplateVars returns the sorted template variables in s, with spaces removed, e.g. "{{name}}"
+func TemplateVars(s string) []string {
+	return sortedMatches(templateVarRegexp, s)
+}
+
+// HTMLTags returns the sorted names of all opening and closing HTML tags in s, e.g. "<b>" and "</b>"
+func HTMLTags(s string) []string {
+	var tags []string
+	for _, m := range htmlTagRegexp.FindAllStringSubmatch(s, -1) {
+		tags = append(tags, "<"+m[1]+strings.ToLower(m[2])+">")
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Placeholders returns the byte offsets of all fmt verbs, python placeholders, template variables,
+// HTML tags and HTML entities in s, as pairs of start and end offsets, in the order they appear
+func Placeholders(s string) [][]int {
+	return placeholderRegexp.FindAllStringIndex(s, -1)
+}
+
+// submatch returns the text of the n:th submatch in the submatch indexes m, or an empty string if it didn't match
+func submatch(s string, m []int, n int) string {
+	if m[2*n] < 0 {
+		return ""
+	}
+	return s[m[2*n]:m[2*n+1]]
+}
+
+// sortedMatches returns all matches of re in s, sorted and with spaces removed
+func sortedMatches(re *regexp.Regexp, s string) []string {
+	var matches []string
+	for _, m := range re.FindAllString(s, -1) {
+		matches = append(matches, strings.ReplaceAll(m, " ", ""))
+	}
+	sort.Strings(matches)
+	return matches
+}
@@ -0,0 +1,24 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoFlags(t *testing.T) {
+	require.Equal(t, []string{"go-format"}, GoFlags("%d files"))
+	require.Equal(t, []string{"go-format"}, GoFlags("% d files"))
+	require.Equal(t, []string{"go-format"}, GoFlags("Width: %dpx"))
+	require.Empty(t, GoFlags("10% off"))
+	require.Empty(t, GoFlags("50% discount", "100%% sure"))
+
+	require.Equal(t, []string{"%d", "%s"}, GoVerbs("%d% of %s"))
+	require.Equal(t, []string{"%[1]s", "%[2]d"}, GoVerbs("%[2]d %[1]s"))
+}
+
+func TestTemplateFlags(t *testing.T) {
+	require.Equal(t, []string{"python-format"}, TemplateFlags("Hello %(name)s"))
+	require.Empty(t, TemplateFlags("Hello {{ name }}"))
+	require.Empty(t, TemplateFlags("10% off"))
+}
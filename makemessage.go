@@ -1,134 +1,564 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"go/build"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
+	"github.com/yzzyx/makemessage/extract"
+	"github.com/yzzyx/makemessage/po"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
 	templatePaths      = pflag.StringSliceP("template-paths", "t", []string{}, "paths to template directories to parse")
 	templateExtensions = pflag.StringSliceP("template-extensions", "e", []string{".html"}, "extensions of template files")
+	scriptExtensions   = pflag.StringSlice("script-extensions", []string{".js", ".mjs", ".jsx", ".ts", ".tsx"}, "extensions of javascript and typescript files in template directories")
+	extractorMap       = pflag.StringSlice("extractor", []string{}, "extractor to use for files with an extension in template directories, as .ext=name (django, gotemplate or javascript)")
 	packagePaths       = pflag.StringSliceP("package-paths", "p", []string{}, "paths to go packages to parse (use '.' to parse the current directory)")
 	recurse            = pflag.BoolP("recursive", "r", false, "recurse into sub-packages")
 	outputPath         = pflag.StringP("output", "o", "locales", "directory to place message files in")
 	languages          = pflag.StringSliceP("languages", "l", []string{}, "languages to process")
-
-	header = `# SOME DESCRIPTIVE TITLE.
-# Copyright (C) YEAR THE PACKAGE'S COPYRIGHT HOLDER
-# This file is distributed under the same license as the PACKAGE package.
-# FIRST AUTHOR <EMAIL@ADDRESS>, YEAR.
-#
-#, fuzzy
-msgid ""
-msgstr ""
-"Project-Id-Version: PACKAGE VERSION\n"
-"Report-Msgid-Bugs-To: \n"
-"POT-Creation-Date: 2019-11-29 14:11+0000\n"
-"PO-Revision-Date: YEAR-MO-DA HO:MI+ZONE\n"
-"Last-Translator: FULL NAME <EMAIL@ADDRESS>\n"
-"Language-Team: LANGUAGE <LL@li.org>\n"
-"Language: \n"
-"MIME-Version: 1.0\n"
-"Content-Type: text/plain; charset=UTF-8\n"
-"Content-Transfer-Encoding: 8bit\n"
-"Plural-Forms: nplurals=2; plural=(n != 1);\n"
-`
+	keywords           = pflag.StringArrayP("keyword", "k", []string{}, "additional translation function, as [path.]Name[:argtype,...] or (path.Type).Name[:argtype,...] (may be repeated)")
+	packageDomains     = pflag.StringArray("package-domain", []string{}, "domain of strings in packages matching an import path, as importpath=domain, where ... matches anything (may be repeated)")
+	templateDomains    = pflag.StringArray("template-domain", []string{}, "domain of strings in templates matching a path, as glob=domain, where ** matches any folders (may be repeated)")
+	statsFormat        = pflag.String("stats-format", "table", "output format of the stats command (table or json)")
+	minCoverage        = pflag.Float64("min-coverage", 0, "minimum percentage of translated entries required by the stats command")
+	jsonFormat         = pflag.String("json-format", "flat", "output format of the export-json command (flat, nested, jed or i18next)")
+	jsonOutputPath     = pflag.String("json-output", "", "directory to place JSON files in (defaults to the output directory)")
+	xliffVersion       = pflag.String("xliff-version", "1.2", "XLIFF version written by the export-xliff command (1.2 or 2.0)")
+	xliffPath          = pflag.String("xliff-path", "", "directory to read and write XLIFF files in (defaults to the output directory)")
+	sourceLanguage     = pflag.String("source-language", "en", "language of the msgids, used in exported XLIFF files")
+	overwrite          = pflag.Bool("overwrite", false, "let import-xliff replace existing translations that differ from the imported ones")
+	columns            = pflag.Bool("columns", false, "include column numbers in references, as file:line:col (not understood by all gettext tools)")
+	addLocation        = pflag.String("add-location", "full", "how to write references to where strings were found (full, file or never)")
+	sortReferences     = pflag.Bool("sort-references", false, "sort the references of each message by file and line")
+	sortBy             = pflag.String("sort-by", "location", "order of messages in message files (location, msgid or existing)")
+	fuzzyMatching      = pflag.Bool("fuzzy-matching", false, "reuse translations of similar messages for new messages, marked as fuzzy")
+	fuzzyThreshold     = pflag.Float64("fuzzy-threshold", po.DefaultFuzzyThreshold, "similarity between 0 and 1 required to reuse the translation of a similar message")
+	obsolete           = pflag.String("obsolete", "keep", "what to do with messages no longer found in the source (keep, drop or expire)")
+	obsoleteRuns       = pflag.Int("obsolete-runs", 0, "with --obsolete=expire, drop obsolete messages after this many runs")
+	obsoleteDays       = pflag.Int("obsolete-days", 0, "with --obsolete=expire, drop obsolete messages after this many days")
+	tmPath             = pflag.String("tm", "", "translation memory file, written by build-tm, used to translate new messages")
+	pseudoLocales      = pflag.StringSlice("pseudo-locale", []string{}, "pseudo-locale, such as en_XA, to write generated pseudo-translations for (may be repeated)")
+	noCache            = pflag.Bool("no-cache", false, "parse all files, instead of reusing the strings found in unchanged files in earlier runs (entries unused for 30 days are removed, and go packages in a workspace or with local replace directives are never cached)")
+	cacheDir           = pflag.String("cache-dir", "", "directory to cache the strings found in each file in (defaults to makemessage in the user's cache directory)")
+	jobs               = pflag.IntP("jobs", "j", 0, "number of templates to parse, and message files to update, at the same time (defaults to the number of CPUs)")
+	showTimings        = pflag.Bool("timings", false, "show the time spent on each step on stderr")
+	watch              = pflag.Bool("watch", false, "keep running, and update the message files when packages or templates change")
+	findWrappers       = pflag.Bool("detect-wrappers", false, "treat functions passing their arguments on to translation functions as keywords")
 )
 
-func main() {
-	var err error
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage of %s: [command] [flags]
+
+Commands:
+  extract   extract strings and update message files (default)
+  lint      check translations in message files for mismatched placeholders
+  stats     show translation statistics per language and domain
+  export-json
+            export translations in message files as JSON
+  export-xliff
+            export message files as XLIFF
+  import-xliff
+            import translations from XLIFF into message files
+  build-tm  build a translation memory from message files (given as arguments,
+            or in the output directory)
 
+Flags:
+`, os.Args[0])
+	pflag.PrintDefaults()
+}
+
+func main() {
+	pflag.Usage = usage
 	pflag.Parse()
 
-	msgHolder := &MsgHolder{
-		strings: map[string][]TranslationString{},
+	switch pflag.Arg(0) {
+	case "", "extract":
+		requireLanguages()
+		extractCommand()
+	case "lint":
+		requireLanguages()
+		lint()
+	case "stats":
+		requireLanguages()
+		stats()
+	case "export-json":
+		requireLanguages()
+		exportJSONCommand()
+	case "export-xliff":
+		requireLanguages()
+		exportXLIFFCommand()
+	case "import-xliff":
+		requireLanguages()
+		importXLIFFCommand()
+	case "build-tm":
+		buildTMCommand()
+	default:
+		fmt.Printf("Unknown command '%s'\n", pflag.Arg(0))
+		os.Exit(2)
 	}
+}
 
+// requireLanguages exits with an error if no languages are given, for the commands that process them
+func requireLanguages() {
 	if len(*languages) == 0 {
-		fmt.Println("At least one language must be specified")
-		return
+		fmt.Fprintln(os.Stderr, "At least one language must be specified")
+		os.Exit(2)
 	}
+}
 
-	if len(*packagePaths) == 0 && len(*templatePaths) == 0 {
-		fmt.Println("At least one package path or template path must be specified")
-		return
+// lint checks all message files for the selected languages, and exits with
+// a non-zero status if any problems are found
+func lint() {
+	issues, err := po.LintFiles(*outputPath, *languages)
+	if err != nil {
+		fmt.Println("Cannot check messages:", err)
+		os.Exit(1)
+	}
 
+	for _, issue := range issues {
+		fmt.Println(issue)
 	}
 
-	for _, p := range *packagePaths {
-		folderList := []string{p}
+	if len(issues) > 0 {
+		os.Exit(1)
+	}
+}
+
+// stats shows statistics for all message files of the selected languages, and exits with
+// a non-zero status if any of them have a lower coverage than required
+func stats() {
+	catalogs, err := po.CollectStats(*outputPath, *languages)
+	if err != nil {
+		fmt.Println("Cannot read messages:", err)
+		os.Exit(1)
+	}
 
-		basePath, err := filepath.Abs(p)
+	err = po.WriteStats(os.Stdout, catalogs, *statsFormat)
+	if err != nil {
+		fmt.Println("Cannot write statistics:", err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, s := range catalogs {
+		if s.Coverage < *minCoverage && s.Domain == "" {
+			fmt.Fprintf(os.Stderr, "%s: no message files found, coverage is below the required %.1f%%\n", s.Language, *minCoverage)
+			failed = true
+		} else if s.Coverage < *minCoverage {
+			fmt.Fprintf(os.Stderr, "%s/%s.po: coverage %.1f%% is below the required %.1f%%\n", s.Language, s.Domain, s.Coverage, *minCoverage)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// exportJSONCommand exports the message files of the selected languages as JSON
+func exportJSONCommand() {
+	jsonPath := *jsonOutputPath
+	if jsonPath == "" {
+		jsonPath = *outputPath
+	}
+
+	err := po.ExportJSONFiles(*outputPath, jsonPath, *languages, *jsonFormat)
+	if err != nil {
+		fmt.Println("Cannot export messages:", err)
+		os.Exit(1)
+	}
+}
+
+// exportXLIFFCommand exports the message files of the selected languages as XLIFF
+func exportXLIFFCommand() {
+	path := *xliffPath
+	if path == "" {
+		path = *outputPath
+	}
+
+	err := po.ExportXLIFFFiles(*outputPath, path, *languages, *xliffVersion, *sourceLanguage)
+	if err != nil {
+		fmt.Println("Cannot export messages:", err)
+		os.Exit(1)
+	}
+}
+
+// importXLIFFCommand imports translations from XLIFF into the message files of the selected
+// languages, and exits with a non-zero status if any conflicts were found
+func importXLIFFCommand() {
+	path := *xliffPath
+	if path == "" {
+		path = *outputPath
+	}
+
+	conflicts, err := po.ImportXLIFFFiles(*outputPath, path, *languages, *overwrite)
+	if err != nil {
+		fmt.Println("Cannot import messages:", err)
+		os.Exit(1)
+	}
+
+	for _, c := range conflicts {
+		fmt.Println(c)
+	}
+
+	if len(conflicts) > 0 {
+		os.Exit(1)
+	}
+}
+
+// buildTMCommand builds a translation memory from the message files given as arguments,
+// or from the output directory, for the selected languages
+func buildTMCommand() {
+	if *tmPath == "" {
+		fmt.Println("The path of the translation memory must be specified with --tm")
+		os.Exit(1)
+	}
+
+	paths := pflag.Args()[1:]
+	if len(paths) == 0 {
+		paths = []string{*outputPath}
+	}
+
+	tm, err := po.BuildTranslationMemory(paths, *languages)
+	if err != nil {
+		fmt.Println("Cannot read messages:", err)
+		os.Exit(1)
+	}
+
+	err = po.WriteTranslationMemory(*tmPath, tm)
+	if err != nil {
+		fmt.Println("Cannot write translation memory:", err)
+		os.Exit(1)
+	}
+}
+
+// goOptions returns the options for parsing go packages, as given by the flags
+func goOptions() (extract.GoOptions, error) {
+	opts := extract.GoOptions{
+		Keywords:       extract.DefaultKeywords,
+		DetectWrappers: *findWrappers,
+		Log:            os.Stderr,
+	}
+	for _, k := range *keywords {
+		keyword, err := extract.ParseKeyword(k)
+		if err != nil {
+			return opts, err
+		}
+		opts.Keywords = append(opts.Keywords, keyword)
+	}
+
+	for _, r := range *packageDomains {
+		rule, err := extract.ParsePackageDomainRule(r)
 		if err != nil {
-			fmt.Printf("Cannot get absolute path of %s: %v\n", p, err)
-			return
+			return opts, err
 		}
+		opts.Domains.Packages = append(opts.Domains.Packages, rule)
+	}
 
-		if *recurse {
-			// Include all underlying packages as well
-			err = filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
-				if !info.Mode().IsDir() {
-					return nil
-				}
+	for _, r := range *templateDomains {
+		rule, err := extract.ParseTemplateDomainRule(r)
+		if err != nil {
+			return opts, err
+		}
+		opts.Domains.Templates = append(opts.Domains.Templates, rule)
+	}
+	return opts, nil
+}
 
-				if strings.HasPrefix(info.Name(), ".") {
-					return filepath.SkipDir
-				}
+// packageFolders returns the absolute path of the package path p, and the folders of
+// all packages to parse in it, including underlying packages if --recursive is set
+func packageFolders(p string) (string, []string, error) {
+	folderList := []string{p}
 
-				if _, err = build.ImportDir(path, 0); err != nil {
-					if _, noGo := err.(*build.NoGoError); !noGo {
-						log.Print(err)
-					}
-					return nil
+	basePath, err := filepath.Abs(p)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot get absolute path of %s: %w", p, err)
+	}
+
+	if *recurse {
+		// Include all underlying packages as well
+		err = filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
+			if !info.Mode().IsDir() {
+				return nil
+			}
+
+			if strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			if _, err = build.ImportDir(path, 0); err != nil {
+				if _, noGo := err.(*build.NoGoError); !noGo {
+					log.Print(err)
 				}
-				folderList = append(folderList, path)
 				return nil
-			})
-			if err != nil {
-				fmt.Println("Error recursing into folders:", err)
-				return
 			}
+			folderList = append(folderList, path)
+			return nil
+		})
+		if err != nil {
+			return "", nil, fmt.Errorf("error recursing into folders: %w", err)
 		}
-		err = parseGo(basePath, folderList, msgHolder)
+	}
+	return basePath, folderList, nil
+}
+
+// parsePackages parses the go packages in folders, relative to basePath, and adds the strings found to msgHolder.
+// The strings are also added to goStrings, by the absolute folder of the package they were found in.
+func parsePackages(basePath string, folders []string, msgHolder *extract.MsgHolder,
+	goStrings map[string][]extract.TranslationString, opts extract.GoOptions) error {
+	found := extract.NewMsgHolder()
+	err := extract.ParseGo(basePath, folders, found, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, domain := range found.Domains() {
+		for _, s := range found.Strings(domain) {
+			dir := filepath.Join(basePath, filepath.Dir(s.Path))
+			goStrings[dir] = append(goStrings[dir], s)
+			msgHolder.Add(s)
+		}
+	}
+	return nil
+}
+
+// extractorMapping returns the extractor to use for each file extension in template directories.
+// Keywords without a package or type are extracted from go templates as well.
+func extractorMapping(keywords []extract.Keyword) (map[string]extract.Extractor, error) {
+	extractors := extract.DefaultExtractors()
+	extractors["gotemplate"] = extract.GoTemplateExtractor{Keywords: keywords}
+	return extract.ExtractorMapping(extractors, map[string][]string{
+		"django":     *templateExtensions,
+		"javascript": *scriptExtensions,
+	}, *extractorMap)
+}
+
+// newCache returns the cache for the strings found in each file, or nil if --no-cache is set
+func newCache() (*extract.Cache, error) {
+	if *noCache {
+		return nil, nil
+	}
+
+	dir := *cacheDir
+	if dir == "" {
+		var err error
+		dir, err = extract.DefaultCacheDir()
 		if err != nil {
-			fmt.Println("Error parsing packages:", err)
-			return
+			return nil, err
 		}
 	}
+	return extract.NewCache(dir)
+}
 
-	allowedExtensions := map[string]bool{}
-	for _, ext := range *templateExtensions {
-		allowedExtensions[ext] = true
+// extractTemplate adds the strings in the file at path to msgHolder, if there is an extractor for it.
+// If cache is set, the strings are only extracted if the file has changed since it was cached.
+// Strings without a domain are put in the domain given by the first matching rule in rules.
+func extractTemplate(mapping map[string]extract.Extractor, cache *extract.Cache, rules extract.DomainRules, path string, msgHolder *extract.MsgHolder) error {
+	extractor, ok := mapping[filepath.Ext(path)]
+	if !ok {
+		return nil
 	}
 
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	strs, err := cache.Extract(extractor, path, content)
+	var scriptErr *extract.ScriptError
+	var warnings extract.Warnings
+	switch {
+	case errors.As(err, &scriptErr):
+		// A script that cannot be parsed should not stop the strings in all other files from being updated
+		fmt.Fprintf(os.Stderr, "%s: skipping file: %v\n", path, scriptErr.Err)
+		return nil
+	case errors.As(err, &warnings):
+		for _, w := range warnings {
+			fmt.Fprintln(os.Stderr, w)
+		}
+	case err != nil:
+		return err
+	}
+
+	domain := rules.TemplateDomain(path)
+	for _, s := range strs {
+		if s.Domain == "" {
+			s.Domain = domain
+		}
+		msgHolder.Add(s)
+	}
+	return nil
+}
+
+// extractTemplates adds the strings in all files in the template paths to msgHolder,
+// parsing up to --jobs files at the same time
+func extractTemplates(mapping map[string]extract.Extractor, cache *extract.Cache, rules extract.DomainRules, msgHolder *extract.MsgHolder) error {
+	var paths []string
 	for _, p := range *templatePaths {
-		err = filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
-			if !info.Mode().IsRegular() {
-				return nil
+		err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
 			}
-
-			if !allowedExtensions[filepath.Ext(path)] {
-				return nil
+			if info.Mode().IsRegular() {
+				paths = append(paths, path)
 			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
 
-			return parseTemplate(path, msgHolder)
+	g := errgroup.Group{}
+	g.SetLimit(numJobs())
+	for _, path := range paths {
+		g.Go(func() error {
+			return extractTemplate(mapping, cache, rules, path, msgHolder)
 		})
+	}
+	return g.Wait()
+}
 
+// numJobs returns the number of tasks to run at the same time
+func numJobs() int {
+	if *jobs <= 0 {
+		return runtime.NumCPU()
+	}
+	return *jobs
+}
+
+// writeMessages updates the message files of the given domains with the strings in msgHolder,
+// and prints the changes made. sameRun is set if the files have already been written by this run.
+func writeMessages(msgHolder *extract.MsgHolder, domains []string, tm *po.TranslationMemory, sameRun bool) error {
+	templates := map[string]*po.File{}
+	for _, domain := range domains {
+		_, conflicts := msgHolder.Messages(domain)
+		for _, c := range conflicts {
+			fmt.Fprintln(os.Stderr, c)
+		}
+		templates[domain] = msgHolder.Template(domain, extract.TemplateOptions{Columns: *columns})
+	}
+
+	report, err := po.WriteOutput(*outputPath, *languages, templates, po.OutputOptions{
+		AddLocation:    *addLocation,
+		SortReferences: *sortReferences,
+		SortBy:         *sortBy,
+		Merge: po.MergeOptions{
+			FuzzyMatching:  *fuzzyMatching,
+			FuzzyThreshold: *fuzzyThreshold,
+		},
+		Obsolete: po.ObsoletePolicy{
+			Mode:    *obsolete,
+			MaxRuns: *obsoleteRuns,
+			MaxDays: *obsoleteDays,
+			SameRun: sameRun,
+		},
+		TranslationMemory: tm,
+		PseudoLocales:     *pseudoLocales,
+		Jobs:              numJobs(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, r := range report.Retired {
+		fmt.Println(r)
+	}
+	for _, r := range report.Reused {
+		fmt.Println(r)
+	}
+	return nil
+}
+
+// extractCommand extracts strings from all packages and templates, and updates the message files
+func extractCommand() {
+	start := time.Now()
+	msgHolder := extract.NewMsgHolder()
+
+	if len(*packagePaths) == 0 && len(*templatePaths) == 0 {
+		fmt.Fprintln(os.Stderr, "At least one package path or template path must be specified")
+		os.Exit(2)
+	}
+
+	opts, err := goOptions()
+	if err != nil {
+		fmt.Println("Invalid option:", err)
+		os.Exit(1)
+	}
+
+	opts.Cache, err = newCache()
+	if err != nil {
+		fmt.Println("Cannot create cache:", err)
+		os.Exit(1)
+	}
+
+	if *showTimings {
+		opts.Timings = &extract.Timings{}
+	}
+
+	goStrings := map[string][]extract.TranslationString{}
+	for _, p := range *packagePaths {
+		basePath, folderList, err := packageFolders(p)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		err = parsePackages(basePath, folderList, msgHolder, goStrings, opts)
+		if err != nil {
+			fmt.Println("Error parsing packages:", err)
+			os.Exit(1)
+		}
+	}
+
+	mapping, err := extractorMapping(opts.Keywords)
+	if err != nil {
+		fmt.Println("Invalid extractor:", err)
+		os.Exit(1)
+	}
+
+	templateStart := time.Now()
+	err = extractTemplates(mapping, opts.Cache, opts.Domains, msgHolder)
+	if err != nil {
+		fmt.Println("cannot process files:", err)
+		os.Exit(1)
+	}
+	opts.Timings.Since("parse templates", templateStart)
+
+	var tm *po.TranslationMemory
+	if *tmPath != "" {
+		tm, err = po.ReadTranslationMemory(*tmPath)
 		if err != nil {
-			fmt.Println("cannot process files:", err)
-			return
+			fmt.Println("Cannot read translation memory:", err)
+			os.Exit(1)
 		}
 	}
 
-	err = msgHolder.WriteOutput(*outputPath, *languages)
+	writeStart := time.Now()
+	err = writeMessages(msgHolder, msgHolder.Domains(), tm, false)
 	if err != nil {
 		fmt.Println("Cannot create messages:", err)
-		return
+		os.Exit(1)
+	}
+	opts.Timings.Since("update message files", writeStart)
+	opts.Timings.Since("total", start)
+	opts.Timings.Write(os.Stderr)
+
+	if *watch {
+		err = watchFiles(msgHolder, goStrings, opts, mapping, tm)
+		if err != nil {
+			fmt.Println("Cannot watch files:", err)
+			os.Exit(1)
+		}
 	}
 }
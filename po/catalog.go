@@ -0,0 +1,41 @@
+package po
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// catalog is a PO file read from "<outputFolder>/<lang>/<domain>.po"
+type catalog struct {
+	Path     string
+	Language string
+	Domain   string
+	File     *File
+}
+
+// readCatalogs reads the PO files of the given languages in outputFolder, in the order of
+// languages, and then by domain. Languages without any PO files are skipped.
+func readCatalogs(outputFolder string, languages []string) ([]catalog, error) {
+	var catalogs []catalog
+	for _, lang := range languages {
+		paths, err := filepath.Glob(filepath.Join(outputFolder, lang, "*.po"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range paths {
+			f, err := ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+
+			catalogs = append(catalogs, catalog{
+				Path:     path,
+				Language: lang,
+				Domain:   strings.TrimSuffix(filepath.Base(path), ".po"),
+				File:     f,
+			})
+		}
+	}
+	return catalogs, nil
+}
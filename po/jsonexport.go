@@ -0,0 +1,141 @@
+package po
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// contextSeparator separates the context from the msgid in keys, as done by gettext in MO files
+const contextSeparator = "\u0004"
+
+// jsonEntries returns all entries of f that should be exported, i.e. all
+// translated entries that are not fuzzy or obsolete
+func (f *File) jsonEntries() []*Entry {
+	var entries []*Entry
+	for _, e := range f.Entries {
+		if e.IsHeader() || e.Obsolete || e.HasFlag("fuzzy") || !e.IsTranslated() {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// jsonValue returns the translation of e as a string, or as a list of strings if e has plural forms
+func jsonValue(e *Entry) interface{} {
+	if e.IDPlural == "" {
+		return e.Str[0]
+	}
+	return e.Str
+}
+
+// ExportJSON converts f to the given JSON format:
+//
+//   - flat: {"msgid": "msgstr"}, with the context prepended to the msgid as "context\u0004msgid"
+//   - nested: {"context": {"msgid": "msgstr"}}, with entries without context placed under ""
+//   - jed: the Jed 1.x locale_data format, as used by Jed and Django's JavaScript catalog
+//   - i18next: the i18next v3 JSON format, using "msgid_context" and "msgid_plural" or "msgid_N" keys
+//
+// In the flat and nested formats, entries with plural forms are written as lists of strings.
+func ExportJSON(f *File, format string, lang string, domain string) (interface{}, error) {
+	switch format {
+	case "flat":
+		out := map[string]interface{}{}
+		for _, e := range f.jsonEntries() {
+			key := e.ID
+			if e.Context != "" {
+				key = e.Context + contextSeparator + e.ID
+			}
+			out[key] = jsonValue(e)
+		}
+		return out, nil
+	case "nested":
+		out := map[string]map[string]interface{}{}
+		for _, e := range f.jsonEntries() {
+			if out[e.Context] == nil {
+				out[e.Context] = map[string]interface{}{}
+			}
+			out[e.Context][e.ID] = jsonValue(e)
+		}
+		return out, nil
+	case "jed":
+		messages := map[string]interface{}{
+			"": map[string]string{
+				"domain":       domain,
+				"lang":         lang,
+				"plural_forms": f.HeaderField("Plural-Forms"),
+			},
+		}
+		for _, e := range f.jsonEntries() {
+			key := e.ID
+			if e.Context != "" {
+				key = e.Context + contextSeparator + e.ID
+			}
+			messages[key] = e.Str
+		}
+		return map[string]interface{}{
+			"domain":      domain,
+			"locale_data": map[string]interface{}{domain: messages},
+		}, nil
+	case "i18next":
+		out := map[string]string{}
+		nplurals := f.PluralCount()
+		for _, e := range f.jsonEntries() {
+			key := e.ID
+			if e.Context != "" {
+				key += "_" + e.Context
+			}
+
+			switch {
+			case e.IDPlural == "":
+				out[key] = e.Str[0]
+			case nplurals == 2 && len(e.Str) == 2:
+				out[key] = e.Str[0]
+				out[key+"_plural"] = e.Str[1]
+			default:
+				for i, s := range e.Str {
+					out[fmt.Sprintf("%s_%d", key, i)] = s
+				}
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unknown JSON format '%s'", format)
+	}
+}
+
+// ExportJSONFiles converts all PO files of the given languages in outputFolder to JSON,
+// and writes them to "<jsonFolder>/<lang>/<domain>.json"
+func ExportJSONFiles(outputFolder string, jsonFolder string, languages []string, format string) error {
+	catalogs, err := readCatalogs(outputFolder, languages)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range catalogs {
+		out, err := ExportJSON(c.File, format, c.Language, c.Domain)
+		if err != nil {
+			return err
+		}
+
+		b, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		langFolder := filepath.Join(jsonFolder, c.Language)
+		err = os.MkdirAll(langFolder, 0755)
+		if err != nil {
+			return fmt.Errorf("could not create folder '%s': %w", langFolder, err)
+		}
+
+		jsonPath := filepath.Join(langFolder, c.Domain+".json")
+		err = os.WriteFile(jsonPath, append(b, '\n'), 0644)
+		if err != nil {
+			return fmt.Errorf("could not write file '%s': %w", jsonPath, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package po
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const jsonTestPO = `msgid ""
+msgstr ""
+"Language: sv_SE\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "Hello"
+msgstr "Hej"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Öppna"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "En fil"
+msgstr[1] "%d filer"
+
+#, fuzzy
+msgid "Fuzzy"
+msgstr "Luddig"
+
+msgid "Untranslated"
+msgstr ""
+`
+
+func TestExportJSON(t *testing.T) {
+	f, err := Parse(strings.NewReader(jsonTestPO))
+	require.Nil(t, err)
+
+	tests := map[string]string{
+		"flat":    `{"Hello":"Hej","One file":["En fil","%d filer"],"menu\u0004Open":"Öppna"}`,
+		"nested":  `{"":{"Hello":"Hej","One file":["En fil","%d filer"]},"menu":{"Open":"Öppna"}}`,
+		"i18next": `{"Hello":"Hej","One file":"En fil","One file_plural":"%d filer","Open_menu":"Öppna"}`,
+		"jed": `{"domain":"messages","locale_data":{"messages":{"":{"domain":"messages","lang":"sv_SE","plural_forms":"nplurals=2; plural=(n != 1);"},` +
+			`"Hello":["Hej"],"One file":["En fil","%d filer"],"menu\u0004Open":["Öppna"]}}}`,
+	}
+
+	for format, expected := range tests {
+		out, err := ExportJSON(f, format, "sv_SE", "messages")
+		require.Nil(t, err)
+
+		b, err := json.Marshal(out)
+		require.Nil(t, err)
+		require.JSONEq(t, expected, string(b), "unexpected output for format %s", format)
+	}
+
+	_, err = ExportJSON(f, "yaml", "sv_SE", "messages")
+	require.NotNil(t, err)
+}
@@ -0,0 +1,137 @@
+package po
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yzzyx/makemessage/internal/format"
+)
+
+// Issue describes a problem found in a translation
+type Issue struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d: %s", i.Path, i.Line, i.Message)
+}
+
+// LintFiles checks all PO files for the given languages in outputFolder
+func LintFiles(outputFolder string, languages []string) ([]Issue, error) {
+	catalogs, err := readCatalogs(outputFolder, languages)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	for _, c := range catalogs {
+		for _, e := range c.File.Entries {
+			for _, msg := range LintEntry(e) {
+				issues = append(issues, Issue{Path: c.Path, Line: e.Line, Message: msg})
+			}
+		}
+	}
+	return issues, nil
+}
+
+// LintEntry checks that all translations of e have the same placeholders, HTML tags and
+// leading and trailing newlines as the msgid. Plural forms may match either msgid or msgid_plural.
+func LintEntry(e *Entry) []string {
+	if e.IsHeader() || e.Obsolete || e.HasFlag("fuzzy") {
+		return nil
+	}
+
+	var issues []string
+	for i, str := range e.Str {
+		if str == "" {
+			continue
+		}
+
+		name := "msgstr"
+		if e.IDPlural != "" {
+			name = fmt.Sprintf("msgstr[%d]", i)
+		}
+
+		var firstProblems []string
+		for _, source := range []string{e.ID, e.IDPlural} {
+			if source == "" {
+				continue
+			}
+
+			problems := lintString(e, source, str)
+			if len(problems) == 0 {
+				firstProblems = nil
+				break
+			}
+			if firstProblems == nil {
+				firstProblems = problems
+			}
+		}
+
+		for _, p := range firstProblems {
+			issues = append(issues, fmt.Sprintf("%s: %s", name, p))
+		}
+	}
+	return issues
+}
+
+// lintString compares a single translation with its source string
+func lintString(e *Entry, source, translation string) []string {
+	var problems []string
+
+	if !e.HasFlag("no-go-format") && (e.HasFlag("go-format") || format.HasGoVerbs(source)) {
+		expected, actual := format.GoVerbs(source), format.GoVerbs(translation)
+		if !equalStrings(expected, actual) {
+			problems = append(problems, fmt.Sprintf("format verbs differ, expected %s but found %s", listOrNone(expected), listOrNone(actual)))
+		}
+	}
+
+	if !e.HasFlag("no-python-format") {
+		expected, actual := format.PythonPlaceholders(source), format.PythonPlaceholders(translation)
+		if !equalStrings(expected, actual) {
+			problems = append(problems, fmt.Sprintf("placeholders differ, expected %s but found %s", listOrNone(expected), listOrNone(actual)))
+		}
+	}
+
+	if !e.HasFlag("no-python-brace-format") {
+		expected, actual := format.TemplateVars(source), format.TemplateVars(translation)
+		if !equalStrings(expected, actual) {
+			problems = append(problems, fmt.Sprintf("variables differ, expected %s but found %s", listOrNone(expected), listOrNone(actual)))
+		}
+	}
+
+	expectedTags, actualTags := format.HTMLTags(source), format.HTMLTags(translation)
+	if !equalStrings(expectedTags, actualTags) {
+		problems = append(problems, fmt.Sprintf("HTML tags differ, expected %s but found %s", listOrNone(expectedTags), listOrNone(actualTags)))
+	}
+
+	if strings.HasPrefix(source, "\n") != strings.HasPrefix(translation, "\n") {
+		problems = append(problems, "msgid and translation do not both begin with a newline")
+	}
+
+	if strings.HasSuffix(source, "\n") != strings.HasSuffix(translation, "\n") {
+		problems = append(problems, "msgid and translation do not both end with a newline")
+	}
+	return problems
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func listOrNone(l []string) string {
+	if len(l) == 0 {
+		return "none"
+	}
+	return strings.Join(l, " ")
+}
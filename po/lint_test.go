@@ -0,0 +1,32 @@
+package po
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintPOFiles(t *testing.T) {
+	cwd, _ := os.Getwd()
+	issues, err := LintFiles(filepath.Join(cwd, "testdata", "locales"), []string{"sv_SE"})
+	require.Nil(t, err)
+
+	lines := map[int][]string{}
+	for _, issue := range issues {
+		lines[issue.Line] = append(lines[issue.Line], issue.Message)
+	}
+
+	require.NotContains(t, lines, 11, "expected correct translation to pass")
+	require.Equal(t, []string{"msgstr: format verbs differ, expected %s %d but found %s %s"}, lines[17])
+	require.NotContains(t, lines, 22, "expected plural forms to match either msgid or msgid_plural")
+	require.Equal(t, []string{"msgstr: variables differ, expected {{user.name}} but found {{user.namn}}"}, lines[29])
+	require.Equal(t, []string{"msgstr: HTML tags differ, expected </a> <a> but found <a>"}, lines[33])
+	require.Equal(t, []string{
+		"msgstr: msgid and translation do not both begin with a newline",
+		"msgstr: msgid and translation do not both end with a newline",
+	}, lines[37])
+	require.NotContains(t, lines, 45, "expected fuzzy entries to be ignored")
+	require.Len(t, issues, 5)
+}
@@ -0,0 +1,216 @@
+package po
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultFuzzyThreshold is the similarity required for a fuzzy match, if no threshold is given
+const DefaultFuzzyThreshold = 0.7
+
+// MergeOptions controls how templates are merged into existing message files
+type MergeOptions struct {
+	// FuzzyMatching lets new entries reuse the translation of a similar existing entry.
+	// Such entries are marked as fuzzy, with the msgid they were translated from as the previous msgid.
+	FuzzyMatching bool
+
+	// FuzzyThreshold is the similarity, between 0 and 1, required for a fuzzy match (defaults to DefaultFuzzyThreshold)
+	FuzzyThreshold float64
+}
+
+// levenshtein returns the edit distance between a and b, counted in runes
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// tokenSimilarity returns the Dice coefficient of the words in a and b
+func tokenSimilarity(a, b string) float64 {
+	wordsA, wordsB := strings.Fields(strings.ToLower(a)), strings.Fields(strings.ToLower(b))
+	if len(wordsA)+len(wordsB) == 0 {
+		return 1
+	}
+
+	counts := map[string]int{}
+	for _, w := range wordsA {
+		counts[w]++
+	}
+
+	common := 0
+	for _, w := range wordsB {
+		if counts[w] > 0 {
+			counts[w]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(wordsA)+len(wordsB))
+}
+
+// Similarity returns how similar a and b are, between 0 and 1. It is the highest of the
+// edit distance similarity of the characters, and the share of words the strings have in common,
+// so that both small typo fixes and reordered words are matched.
+func Similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+
+	longest := max(utf8.RuneCountInString(a), utf8.RuneCountInString(b))
+	charSimilarity := 1 - float64(levenshtein([]rune(a), []rune(b)))/float64(longest)
+	return max(charSimilarity, tokenSimilarity(a, b))
+}
+
+// fuzzyMatch returns the translated entry in candidates most similar to e, with a similarity of at
+// least threshold. Entries with the same context as e are preferred. Returns nil if there is no match.
+func fuzzyMatch(e *Entry, candidates []*Entry, threshold float64) *Entry {
+	var best *Entry
+	for _, sameContext := range []bool{true, false} {
+		bestSimilarity := threshold
+		for _, c := range candidates {
+			if (c.Context == e.Context) != sameContext || !c.IsTranslated() || c.IsHeader() {
+				continue
+			}
+
+			if s := Similarity(e.ID, c.ID); s >= bestSimilarity && (best == nil || s > bestSimilarity) {
+				best, bestSimilarity = c, s
+			}
+		}
+
+		if best != nil {
+			return best
+		}
+	}
+	return nil
+}
+
+// pluralStrings returns a copy of strs with the number of forms used by an entry
+func pluralStrings(strs []string, hasPlural bool, nplurals int) []string {
+	count := 1
+	if hasPlural {
+		count = nplurals
+	}
+
+	adjusted := make([]string, count)
+	copy(adjusted, strs)
+	return adjusted
+}
+
+// Merge updates the message file existing with the entries in template, and returns the result,
+// in the same way as msgmerge. Entries are written in the order of the template, and keep the
+// header, translations and translator comments of existing. Entries that are no longer in the
+// template are kept as obsolete entries at the end, if they have a translation.
+func Merge(existing, template *File, opts MergeOptions) *File {
+	threshold := opts.FuzzyThreshold
+	if threshold <= 0 {
+		threshold = DefaultFuzzyThreshold
+	}
+
+	nplurals := existing.PluralCount()
+
+	index := map[string]*Entry{}
+	for _, e := range existing.Entries {
+		key := e.Context + contextSeparator + e.ID
+
+		// Active entries take precedence over obsolete ones
+		if prev, ok := index[key]; !ok || (prev.Obsolete && !e.Obsolete) {
+			index[key] = e
+		}
+	}
+
+	merged := &File{}
+	if header := existing.Header(); header != nil {
+		merged.Entries = append(merged.Entries, header)
+	} else if header := template.Header(); header != nil {
+		// The template is shared by all languages, so it must not be changed through the merged file
+		merged.Entries = append(merged.Entries, header.copy())
+	}
+
+	var candidates []*Entry
+	if opts.FuzzyMatching {
+		for _, e := range existing.Entries {
+			if !e.Obsolete && !e.HasFlag("fuzzy") {
+				candidates = append(candidates, e)
+			}
+		}
+	}
+
+	used := map[*Entry]bool{}
+	for _, t := range template.Entries {
+		if t.IsHeader() {
+			continue
+		}
+
+		e := &Entry{
+			ExtractedComments: t.ExtractedComments,
+			References:        t.References,
+			Context:           t.Context,
+			ID:                t.ID,
+			IDPlural:          t.IDPlural,
+		}
+		flags := append([]string(nil), t.Flags...)
+
+		if old, ok := index[t.Context+contextSeparator+t.ID]; ok {
+			used[old] = true
+
+			e.TranslatorComments = old.TranslatorComments
+			e.Str = pluralStrings(old.Str, t.IDPlural != "", nplurals)
+
+			if old.HasFlag("fuzzy") {
+				flags = append(flags, "fuzzy")
+				e.PreviousContext, e.PreviousID, e.PreviousPlural = old.PreviousContext, old.PreviousID, old.PreviousPlural
+			} else if old.IDPlural != t.IDPlural && hasTranslation(old) {
+				// The plural form has changed, so the translation needs to be reviewed
+				flags = append(flags, "fuzzy")
+				e.PreviousContext, e.PreviousID, e.PreviousPlural = old.Context, old.ID, old.IDPlural
+			}
+		} else if match := fuzzyMatch(t, candidates, threshold); match != nil {
+			e.Str = pluralStrings(match.Str, t.IDPlural != "", nplurals)
+			e.PreviousContext, e.PreviousID, e.PreviousPlural = match.Context, match.ID, match.IDPlural
+			flags = append(flags, "fuzzy")
+		} else {
+			e.Str = pluralStrings(nil, t.IDPlural != "", nplurals)
+		}
+
+		e.Flags = flags
+		merged.Entries = append(merged.Entries, e)
+	}
+
+	// Keep the remaining translated entries as obsolete
+	for _, old := range existing.Entries {
+		if used[old] || old.IsHeader() || !hasTranslation(old) {
+			continue
+		}
+
+		e := *old
+		e.Obsolete = true
+		e.References = nil
+		e.ExtractedComments = nil
+		merged.Entries = append(merged.Entries, &e)
+	}
+	return merged
+}
+
+// hasTranslation checks if any form of e has a translation
+func hasTranslation(e *Entry) bool {
+	for _, s := range e.Str {
+		if s != "" {
+			return true
+		}
+	}
+	return false
+}
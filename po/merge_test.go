@@ -0,0 +1,117 @@
+package po
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const mergeExisting = `msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+"Plural-Forms: nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+# Keep this short
+#: old.go:1
+msgid "Save"
+msgstr "Zapisz"
+
+#: old.go:2
+msgid "Delete the selected file"
+msgstr "Usuń wybrany plik"
+
+#: old.go:3
+msgctxt "menu"
+msgid "Open recent files"
+msgstr "Otwórz ostatnie pliki"
+
+#: old.go:4
+msgid "Open recent files"
+msgstr "Otwórz ostatnie"
+
+#: old.go:5
+msgid "Unused"
+msgstr ""
+
+#: old.go:6
+msgid "Gone"
+msgstr "Usunięte"
+`
+
+func TestSimilarity(t *testing.T) {
+	require.Equal(t, 1.0, Similarity("Save", "Save"))
+	require.InDelta(t, 0.95, Similarity("Delete the selected file", "Delete the selected files"), 0.02)
+	require.Equal(t, 1.0, Similarity("files selected", "selected files"))
+	require.Less(t, Similarity("Save", "Cancel"), 0.5)
+}
+
+func TestMerge(t *testing.T) {
+	existing, err := Parse(strings.NewReader(mergeExisting))
+	require.Nil(t, err)
+
+	template := NewTemplate()
+	template.Entries = append(template.Entries,
+		&Entry{ID: "Save", References: []string{"new.go:1"}, ExtractedComments: []string{"TRANSLATORS: Button"}},
+		&Entry{ID: "Delete the selected files", References: []string{"new.go:2"}},
+		&Entry{ID: "Open recent file", Context: "menu", References: []string{"new.go:3"}},
+		&Entry{ID: "One file", IDPlural: "%d files", References: []string{"new.go:4"}},
+	)
+
+	merged := Merge(existing, template, MergeOptions{})
+	require.Len(t, merged.Entries, 9)
+	require.True(t, merged.Entries[0].IsHeader())
+
+	save := merged.Entries[1]
+	require.Equal(t, []string{"Zapisz"}, save.Str)
+	require.Equal(t, []string{"Keep this short"}, save.TranslatorComments)
+	require.Equal(t, []string{"TRANSLATORS: Button"}, save.ExtractedComments)
+	require.Equal(t, []string{"new.go:1"}, save.References)
+	require.Empty(t, save.Flags)
+
+	require.Equal(t, []string{""}, merged.Entries[2].Str)
+	require.Equal(t, []string{"", "", ""}, merged.Entries[4].Str)
+
+	// Translated entries no longer in the template are kept as obsolete
+	var obsolete []string
+	for _, e := range merged.Entries[5:] {
+		require.True(t, e.Obsolete)
+		require.Empty(t, e.References)
+		obsolete = append(obsolete, e.ID)
+	}
+	require.Equal(t, []string{"Delete the selected file", "Open recent files", "Open recent files", "Gone"}, obsolete)
+
+	merged = Merge(existing, template, MergeOptions{FuzzyMatching: true})
+	require.Len(t, merged.Entries, 9)
+
+	deleteFiles := merged.Entries[2]
+	require.Equal(t, []string{"Usuń wybrany plik"}, deleteFiles.Str)
+	require.Equal(t, "Delete the selected file", deleteFiles.PreviousID)
+	require.Equal(t, []string{"fuzzy"}, deleteFiles.Flags)
+
+	// Entries with the same context are preferred
+	openRecent := merged.Entries[3]
+	require.Equal(t, []string{"Otwórz ostatnie pliki"}, openRecent.Str)
+	require.Equal(t, "menu", openRecent.PreviousContext)
+
+	require.False(t, merged.Entries[4].HasFlag("fuzzy"))
+
+	merged = Merge(existing, template, MergeOptions{FuzzyMatching: true, FuzzyThreshold: 0.99})
+	require.False(t, merged.Entries[2].HasFlag("fuzzy"))
+}
+
+func TestMergePluralChange(t *testing.T) {
+	existing, err := Parse(strings.NewReader(`msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "En fil"
+msgstr[1] "%d filer"
+`))
+	require.Nil(t, err)
+
+	template := &File{Entries: []*Entry{{ID: "One file", IDPlural: "%d new files"}}}
+	merged := Merge(existing, template, MergeOptions{})
+	require.Len(t, merged.Entries, 1)
+	require.Equal(t, []string{"En fil", "%d filer"}, merged.Entries[0].Str)
+	require.Equal(t, "%d files", merged.Entries[0].PreviousPlural)
+	require.True(t, merged.Entries[0].HasFlag("fuzzy"))
+}
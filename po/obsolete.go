@@ -0,0 +1,137 @@
+package po
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Policies for obsolete entries, i.e. entries that are no longer found in the source
+const (
+	ObsoleteKeep   = "keep"   // Keep obsolete entries forever
+	ObsoleteDrop   = "drop"   // Drop obsolete entries as soon as they become obsolete
+	ObsoleteExpire = "expire" // Keep obsolete entries for a limited number of runs or days
+)
+
+// obsoleteRegexp matches the translator comment used to track how long an entry has been obsolete
+var obsoleteRegexp = regexp.MustCompile(`^obsolete since (\d{4}-\d{2}-\d{2}), runs: (\d+)$`)
+
+// obsoleteDateFormat is the format of the date in obsolete comments
+const obsoleteDateFormat = "2006-01-02"
+
+// ObsoletePolicy controls what happens to obsolete entries when message files are updated
+type ObsoletePolicy struct {
+	Mode string // One of ObsoleteKeep, ObsoleteDrop or ObsoleteExpire (defaults to ObsoleteKeep)
+
+	// With ObsoleteExpire, entries are dropped when they have been obsolete for more
+	// than MaxRuns runs, or more than MaxDays days. Zero means no limit.
+	MaxRuns int
+	MaxDays int
+
+	// SameRun is set when the message files have already been updated by the current run, e.g. when they
+	// are written again in watch mode, so that the number of runs of obsolete entries is not increased again
+	SameRun bool
+}
+
+func (p ObsoletePolicy) validate() error {
+	switch p.Mode {
+	case "", ObsoleteKeep, ObsoleteDrop:
+		return nil
+	case ObsoleteExpire:
+		if p.MaxRuns <= 0 && p.MaxDays <= 0 {
+			return fmt.Errorf("obsolete policy '%s' requires a maximum number of runs or days", p.Mode)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown obsolete policy '%s', expected %s, %s or %s", p.Mode, ObsoleteKeep, ObsoleteDrop, ObsoleteExpire)
+}
+
+// Retired is an obsolete entry that was dropped from a message file
+type Retired struct {
+	Path  string // Path of the message file
+	Entry *Entry
+}
+
+func (r Retired) String() string {
+	msg := fmt.Sprintf("%s: retired msgid %q", r.Path, r.Entry.ID)
+	if r.Entry.Context != "" {
+		msg += fmt.Sprintf(" (context %q)", r.Entry.Context)
+	}
+	if r.Entry.IsTranslated() {
+		msg += fmt.Sprintf(", translated as %q", r.Entry.Str[0])
+	}
+	return msg
+}
+
+// obsoleteSince returns the date and number of runs from the obsolete comment of e,
+// and the index of the comment. The index is -1 if e has no obsolete comment.
+func (e *Entry) obsoleteSince() (time.Time, int, int) {
+	for i, c := range e.TranslatorComments {
+		m := obsoleteRegexp.FindStringSubmatch(c)
+		if m == nil {
+			continue
+		}
+
+		since, err := time.Parse(obsoleteDateFormat, m[1])
+		if err != nil {
+			continue
+		}
+		runs, _ := strconv.Atoi(m[2])
+		return since, runs, i
+	}
+	return time.Time{}, 0, -1
+}
+
+// ApplyObsoletePolicy drops the obsolete entries of f as given by the policy, and returns them.
+// With ObsoleteExpire, the number of runs and the date an entry became obsolete is tracked in a
+// translator comment, which is updated every time this is called unless p.SameRun is set, and removed if
+// the entry is used again.
+// The return value changed reports whether f was modified.
+func (f *File) ApplyObsoletePolicy(p ObsoletePolicy, now time.Time) (retired []*Entry, changed bool) {
+	if p.Mode == "" || p.Mode == ObsoleteKeep {
+		return nil, false
+	}
+
+	today := now.Format(obsoleteDateFormat)
+
+	n := 0
+	for _, e := range f.Entries {
+		since, runs, idx := e.obsoleteSince()
+
+		switch {
+		case !e.Obsolete:
+			if idx != -1 {
+				e.TranslatorComments = append(e.TranslatorComments[:idx], e.TranslatorComments[idx+1:]...)
+				changed = true
+			}
+		case p.Mode == ObsoleteDrop:
+			retired = append(retired, e)
+			changed = true
+			continue
+		case idx == -1:
+			e.TranslatorComments = append(e.TranslatorComments, fmt.Sprintf("obsolete since %s, runs: 1", today))
+			changed = true
+		case p.SameRun:
+			if p.MaxDays > 0 && now.Sub(since) > time.Duration(p.MaxDays)*24*time.Hour {
+				retired = append(retired, e)
+				changed = true
+				continue
+			}
+		default:
+			runs++
+			if (p.MaxRuns > 0 && runs > p.MaxRuns) || (p.MaxDays > 0 && now.Sub(since) > time.Duration(p.MaxDays)*24*time.Hour) {
+				retired = append(retired, e)
+				changed = true
+				continue
+			}
+			e.TranslatorComments[idx] = fmt.Sprintf("obsolete since %s, runs: %d", since.Format(obsoleteDateFormat), runs)
+			changed = true
+		}
+
+		f.Entries[n] = e
+		n++
+	}
+	f.Entries = f.Entries[:n]
+	return retired, changed
+}
@@ -0,0 +1,92 @@
+package po
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const obsoleteFile = `msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+
+msgid "Active"
+msgstr "Aktiv"
+
+# obsolete since 2026-10-01, runs: 1
+msgid "Used again"
+msgstr "Använd igen"
+
+#~ msgid "New obsolete"
+#~ msgstr "Ny"
+
+# obsolete since 2026-10-01, runs: 2
+#~ msgid "Old obsolete"
+#~ msgstr "Gammal"
+`
+
+func TestApplyObsoletePolicy(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	f, err := Parse(strings.NewReader(obsoleteFile))
+	require.Nil(t, err)
+	retired, changed := f.ApplyObsoletePolicy(ObsoletePolicy{Mode: ObsoleteKeep}, now)
+	require.Empty(t, retired)
+	require.False(t, changed)
+	require.Len(t, f.Entries, 5)
+
+	f, err = Parse(strings.NewReader(obsoleteFile))
+	require.Nil(t, err)
+	retired, changed = f.ApplyObsoletePolicy(ObsoletePolicy{Mode: ObsoleteDrop}, now)
+	require.True(t, changed)
+	require.Len(t, retired, 2)
+	require.Len(t, f.Entries, 3)
+	require.Empty(t, f.Entries[2].TranslatorComments)
+	require.Equal(t, `sv_SE/default.po: retired msgid "New obsolete", translated as "Ny"`, Retired{Path: "sv_SE/default.po", Entry: retired[0]}.String())
+
+	f, err = Parse(strings.NewReader(obsoleteFile))
+	require.Nil(t, err)
+	retired, changed = f.ApplyObsoletePolicy(ObsoletePolicy{Mode: ObsoleteExpire, MaxRuns: 2}, now)
+	require.True(t, changed)
+	require.Len(t, retired, 1)
+	require.Equal(t, "Old obsolete", retired[0].ID)
+	require.Len(t, f.Entries, 4)
+	require.Equal(t, []string{"obsolete since 2026-10-18, runs: 1"}, f.Entries[3].TranslatorComments)
+
+	f, err = Parse(strings.NewReader(obsoleteFile))
+	require.Nil(t, err)
+	retired, _ = f.ApplyObsoletePolicy(ObsoletePolicy{Mode: ObsoleteExpire, MaxRuns: 5, MaxDays: 30}, now)
+	require.Empty(t, retired)
+	require.Equal(t, []string{"obsolete since 2026-10-01, runs: 3"}, f.Entries[4].TranslatorComments)
+
+	retired, _ = f.ApplyObsoletePolicy(ObsoletePolicy{Mode: ObsoleteExpire, MaxDays: 30}, now.AddDate(0, 1, 0))
+	require.Len(t, retired, 2)
+
+	// Writing the same files again in one run, as in watch mode, doesn't count as another run
+	f, err = Parse(strings.NewReader(obsoleteFile))
+	require.Nil(t, err)
+	policy := ObsoletePolicy{Mode: ObsoleteExpire, MaxRuns: 3, MaxDays: 30}
+	retired, _ = f.ApplyObsoletePolicy(policy, now)
+	require.Empty(t, retired)
+
+	policy.SameRun = true
+	for i := 0; i < 5; i++ {
+		retired, changed = f.ApplyObsoletePolicy(policy, now)
+		require.Empty(t, retired)
+		require.False(t, changed)
+	}
+	require.Equal(t, []string{"obsolete since 2026-10-18, runs: 1"}, f.Entries[3].TranslatorComments)
+	require.Equal(t, []string{"obsolete since 2026-10-01, runs: 3"}, f.Entries[4].TranslatorComments)
+
+	retired, _ = f.ApplyObsoletePolicy(policy, now.AddDate(0, 1, 0))
+	require.Len(t, retired, 2)
+}
+
+func TestObsoletePolicyValidate(t *testing.T) {
+	require.Nil(t, ObsoletePolicy{}.validate())
+	require.Nil(t, ObsoletePolicy{Mode: ObsoleteExpire, MaxDays: 10}.validate())
+	require.NotNil(t, ObsoletePolicy{Mode: ObsoleteExpire}.validate())
+	require.NotNil(t, ObsoletePolicy{Mode: "archive"}.validate())
+}
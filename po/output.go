@@ -0,0 +1,331 @@
+package po
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Location modes for references, as used by xgettext's --add-location
+const (
+	LocationFull  = "full"  // "#: file:line"
+	LocationFile  = "file"  // "#: file"
+	LocationNever = "never" // No references
+)
+
+// OutputOptions controls how message files are written
+type OutputOptions struct {
+	AddLocation    string // One of LocationFull, LocationFile or LocationNever (defaults to LocationFull)
+	SortReferences bool   // Sort the references of each entry by file and line
+
+	// SortBy is one of SortByLocation, SortByMsgID or SortByExisting.
+	// If empty, entries are written in the order of the templates.
+	SortBy string
+
+	// Merge controls how templates are merged into existing message files
+	Merge MergeOptions
+
+	// Obsolete controls what happens to entries that are no longer in the templates
+	Obsolete ObsoletePolicy
+
+	// TranslationMemory, if set, is used to translate new entries
+	TranslationMemory *TranslationMemory
+
+	// Jobs is the maximum number of message files to update at the same time (defaults to the number of CPUs)
+	Jobs int
+
+	// PseudoLocales are languages, such as DefaultPseudoLocale, to write pseudo-translations for
+	PseudoLocales []string
+}
+
+// Report describes the changes made to the message files by WriteOutput, for review
+type Report struct {
+	Retired []Retired // Obsolete entries dropped from the message files
+	Reused  []Reused  // Translations taken from the translation memory
+}
+
+// jobs returns the number of message files to update at the same time
+func (opts OutputOptions) jobs() int {
+	if opts.Jobs <= 0 {
+		return runtime.NumCPU()
+	}
+	return opts.Jobs
+}
+
+// validateLocation checks that the location mode is known
+func (opts OutputOptions) validateLocation() error {
+	switch opts.AddLocation {
+	case "", LocationFull, LocationFile, LocationNever:
+		return nil
+	}
+	return fmt.Errorf("unknown location mode '%s', expected %s, %s or %s", opts.AddLocation, LocationFull, LocationFile, LocationNever)
+}
+
+// splitReference splits a reference in the form "file:line" or "file:line:col" into its parts.
+// Line and column are 0 if not present.
+func splitReference(ref string) (file string, line int, column int) {
+	file = ref
+	var numbers []int
+	for len(numbers) < 2 {
+		idx := strings.LastIndexByte(file, ':')
+		if idx == -1 {
+			break
+		}
+		n, err := strconv.Atoi(file[idx+1:])
+		if err != nil {
+			break
+		}
+		numbers = append([]int{n}, numbers...)
+		file = file[:idx]
+	}
+
+	if len(numbers) > 0 {
+		line = numbers[0]
+	}
+	if len(numbers) > 1 {
+		column = numbers[1]
+	}
+	return file, line, column
+}
+
+// FormatReferences returns refs as written with the given options. Depending on the location mode,
+// line numbers are removed, or no references are returned at all.
+func FormatReferences(refs []string, opts OutputOptions) []string {
+	if opts.AddLocation == LocationNever {
+		return nil
+	}
+
+	formatted := make([]string, 0, len(refs))
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		if opts.AddLocation == LocationFile {
+			ref, _, _ = splitReference(ref)
+		}
+
+		if !seen[ref] {
+			seen[ref] = true
+			formatted = append(formatted, ref)
+		}
+	}
+
+	if opts.SortReferences {
+		sort.SliceStable(formatted, func(i, j int) bool {
+			return compareReferences(formatted[i], formatted[j]) < 0
+		})
+	}
+	return formatted
+}
+
+// header is the header entry written to new message templates
+const header = `# SOME DESCRIPTIVE TITLE.
+# Copyright (C) YEAR THE PACKAGE'S COPYRIGHT HOLDER
+# This file is distributed under the same license as the PACKAGE package.
+# FIRST AUTHOR <EMAIL@ADDRESS>, YEAR.
+#
+#, fuzzy
+msgid ""
+msgstr ""
+"Project-Id-Version: PACKAGE VERSION\n"
+"Report-Msgid-Bugs-To: \n"
+"POT-Creation-Date: 2019-11-29 14:11+0000\n"
+"PO-Revision-Date: YEAR-MO-DA HO:MI+ZONE\n"
+"Last-Translator: FULL NAME <EMAIL@ADDRESS>\n"
+"Language-Team: LANGUAGE <LL@li.org>\n"
+"Language: \n"
+"MIME-Version: 1.0\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+"Content-Transfer-Encoding: 8bit\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+`
+
+// NewTemplate returns a message template containing only the default header entry
+func NewTemplate() *File {
+	f, err := Parse(strings.NewReader(header))
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// createFolder creates the folder at path, unless it already exists
+func createFolder(path string) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("stat returned error for folder '%s': %w", path, err)
+		}
+
+		err = os.MkdirAll(path, 0755)
+		if err != nil {
+			return fmt.Errorf("could not create folder '%s': %w", path, err)
+		}
+	} else if !st.IsDir() {
+		return fmt.Errorf("path %s already exists, but is not a directory", path)
+	}
+	return nil
+}
+
+// updateFile updates the message file for lang at domainPath with the entries in template, or creates it
+func updateFile(domainPath string, lang string, template *File, opts OutputOptions, now time.Time) (*Report, error) {
+	report := &Report{}
+
+	poFileExists := false
+	st, err := os.Stat(domainPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("stat returned error for domain path '%s': %w", domainPath, err)
+		}
+	} else {
+		if st.Mode().IsRegular() {
+			poFileExists = true
+		} else {
+			return nil, fmt.Errorf("cannot update path %s - is not a file", domainPath)
+		}
+	}
+
+	existing := &File{}
+	if poFileExists {
+		existing, err = ReadFile(domainPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if poFileExists && opts.SortBy == SortByExisting {
+		// Sort a copy of the template, as the order differs between languages
+		template = &File{Entries: append([]*Entry(nil), template.Entries...)}
+		template.SortLike(existing)
+	}
+
+	merged := Merge(existing, template, opts.Merge)
+
+	retired, _ := merged.ApplyObsoletePolicy(opts.Obsolete, now)
+	for _, e := range retired {
+		report.Retired = append(report.Retired, Retired{Path: domainPath, Entry: e})
+	}
+
+	if opts.TranslationMemory != nil {
+		for _, r := range opts.TranslationMemory.Apply(merged, lang, opts.Merge) {
+			r.Path = domainPath
+			report.Reused = append(report.Reused, r)
+		}
+	}
+
+	err = WriteFile(domainPath, merged)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// WriteOutput writes the message templates, one per domain, to the PO files of all
+// languages in outputFolder. Existing PO files are updated with Merge. The templates
+// must not contain duplicate entries.
+//
+// Up to opts.Jobs PO files are updated at the same time. The templates are not modified while doing so.
+// If a translation memory is given, it's used to translate new entries in both new and existing PO files.
+// The PO files of pseudo-locales are replaced with new pseudo-translations every time.
+func WriteOutput(outputFolder string, languages []string, templates map[string]*File, opts OutputOptions) (*Report, error) {
+	err := opts.validateLocation()
+	if err != nil {
+		return nil, err
+	}
+
+	err = opts.Obsolete.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	now := time.Now()
+
+	// The templates are sorted and formatted once for all languages, on copies, so that
+	// the caller can use them again
+	var domains []string
+	formatted := map[string]*File{}
+	for domain, template := range templates {
+		domains = append(domains, domain)
+
+		template = template.copy()
+		formatted[domain] = template
+
+		// Sort before formatting, so that entries can be sorted by location even if it's not written
+		if opts.SortBy != "" && opts.SortBy != SortByExisting {
+			err = template.Sort(opts.SortBy)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, e := range template.Entries {
+			e.References = FormatReferences(e.References, opts)
+		}
+	}
+	sort.Strings(domains)
+
+	// Each message file is updated separately, and the reports are combined in order afterwards
+	type catalog struct {
+		lang     string
+		path     string
+		template *File
+	}
+	var catalogs []catalog
+	for _, lang := range languages {
+		langFolder := filepath.Join(outputFolder, lang)
+		err = createFolder(langFolder)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, domain := range domains {
+			catalogs = append(catalogs, catalog{
+				lang:     lang,
+				path:     filepath.Join(langFolder, fmt.Sprintf("%s.po", domain)),
+				template: formatted[domain],
+			})
+		}
+	}
+
+	reports := make([]*Report, len(catalogs))
+	g := errgroup.Group{}
+	g.SetLimit(opts.jobs())
+	for i, c := range catalogs {
+		g.Go(func() error {
+			var err error
+			reports[i], err = updateFile(c.path, c.lang, c.template, opts, now)
+			return err
+		})
+	}
+	err = g.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range reports {
+		report.Retired = append(report.Retired, r.Retired...)
+		report.Reused = append(report.Reused, r.Reused...)
+	}
+
+	for _, lang := range opts.PseudoLocales {
+		langFolder := filepath.Join(outputFolder, lang)
+		err = createFolder(langFolder)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, domain := range domains {
+			err = WriteFile(filepath.Join(langFolder, fmt.Sprintf("%s.po", domain)), PseudoFile(formatted[domain], lang))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return report, nil
+}
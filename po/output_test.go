@@ -0,0 +1,125 @@
+package po
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatReferences(t *testing.T) {
+	refs := []string{"views/user.go:100", "views/user.go:9", "admin.go:3:14", "views/user.go:12"}
+
+	require.Equal(t, refs, FormatReferences(refs, OutputOptions{}))
+	require.Equal(t, refs, FormatReferences(refs, OutputOptions{AddLocation: LocationFull}))
+	require.Nil(t, FormatReferences(refs, OutputOptions{AddLocation: LocationNever}))
+
+	require.Equal(t, []string{"views/user.go", "admin.go"}, FormatReferences(refs, OutputOptions{AddLocation: LocationFile}))
+	require.Equal(t, []string{"admin.go", "views/user.go"}, FormatReferences(refs, OutputOptions{AddLocation: LocationFile, SortReferences: true}))
+
+	require.Equal(t,
+		[]string{"admin.go:3:14", "views/user.go:9", "views/user.go:12", "views/user.go:100"},
+		FormatReferences(refs, OutputOptions{SortReferences: true}))
+}
+
+func TestWriteOutput(t *testing.T) {
+	dir := t.TempDir()
+
+	template := NewTemplate()
+	template.Entries = append(template.Entries,
+		&Entry{ID: "Sign in", References: []string{"login.go:10"}},
+		&Entry{ID: "Removed", References: []string{"login.go:20"}},
+	)
+
+	report, err := WriteOutput(dir, []string{"sv_SE"}, map[string]*File{"default": template}, OutputOptions{})
+	require.Nil(t, err)
+	require.Empty(t, report.Retired)
+
+	path := filepath.Join(dir, "sv_SE", "default.po")
+	f, err := ReadFile(path)
+	require.Nil(t, err)
+	require.Len(t, f.Entries, 3)
+
+	f.Entries[1].Str = []string{"Logga in"}
+	f.Entries[2].Str = []string{"Borttagen"}
+	require.Nil(t, WriteFile(path, f))
+
+	template = NewTemplate()
+	template.Entries = append(template.Entries,
+		&Entry{ID: "Sign in!", References: []string{"login.go:30"}},
+		&Entry{ID: "Sign in", References: []string{"login.go:10", "menu.go:5"}},
+	)
+
+	report, err = WriteOutput(dir, []string{"sv_SE"}, map[string]*File{"default": template}, OutputOptions{
+		AddLocation:   LocationFile,
+		SortBy:        SortByMsgID,
+		Merge:         MergeOptions{FuzzyMatching: true},
+		Obsolete:      ObsoletePolicy{Mode: ObsoleteDrop},
+		PseudoLocales: []string{DefaultPseudoLocale},
+	})
+	require.Nil(t, err)
+	require.Len(t, report.Retired, 1)
+
+	// The template is not modified, so that it can be written again
+	require.Equal(t, "Sign in!", template.Entries[1].ID)
+	require.Equal(t, "Sign in", template.Entries[2].ID)
+	require.Equal(t, []string{"login.go:10", "menu.go:5"}, template.Entries[2].References)
+	require.Nil(t, template.Entries[2].Str)
+	require.Equal(t, "Removed", report.Retired[0].Entry.ID)
+
+	f, err = ReadFile(path)
+	require.Nil(t, err)
+	require.Len(t, f.Entries, 3)
+	require.Equal(t, []string{"login.go", "menu.go"}, f.Entries[1].References)
+	require.Equal(t, []string{"Logga in"}, f.Entries[1].Str)
+	require.Equal(t, "Sign in!", f.Entries[2].ID)
+	require.Equal(t, "Sign in", f.Entries[2].PreviousID)
+	require.True(t, f.Entries[2].HasFlag("fuzzy"))
+
+	f, err = ReadFile(filepath.Join(dir, DefaultPseudoLocale, "default.po"))
+	require.Nil(t, err)
+	require.Len(t, f.Entries, 3)
+	require.Equal(t, []string{"[Šîĝñ îñ! ~~~~~~]"}, f.Entries[2].Str)
+
+	_, err = WriteOutput(dir, []string{"sv_SE"}, map[string]*File{"default": template}, OutputOptions{AddLocation: "lines"})
+	require.NotNil(t, err)
+}
+
+func TestWriteOutputJobs(t *testing.T) {
+	dir := t.TempDir()
+	languages := []string{"da", "nb", "sv"}
+	domains := []string{"admin", "default"}
+
+	for _, lang := range languages {
+		require.Nil(t, os.MkdirAll(filepath.Join(dir, lang), 0755))
+		for _, domain := range domains {
+			require.Nil(t, os.WriteFile(filepath.Join(dir, lang, domain+".po"), []byte(`msgid "Removed"
+msgstr "`+lang+`"
+`), 0644))
+		}
+	}
+
+	templates := map[string]*File{}
+	for _, domain := range domains {
+		templates[domain] = NewTemplate()
+		templates[domain].Entries = append(templates[domain].Entries, &Entry{ID: "Sign in"})
+	}
+
+	report, err := WriteOutput(dir, languages, templates, OutputOptions{
+		Obsolete: ObsoletePolicy{Mode: ObsoleteDrop},
+		Jobs:     4,
+	})
+	require.Nil(t, err)
+
+	// The report is in the same order as if the files were updated one at a time
+	var paths []string
+	for _, r := range report.Retired {
+		paths = append(paths, r.Path)
+	}
+	require.Equal(t, []string{
+		filepath.Join(dir, "da", "admin.po"), filepath.Join(dir, "da", "default.po"),
+		filepath.Join(dir, "nb", "admin.po"), filepath.Join(dir, "nb", "default.po"),
+		filepath.Join(dir, "sv", "admin.po"), filepath.Join(dir, "sv", "default.po"),
+	}, paths)
+}
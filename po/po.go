@@ -0,0 +1,459 @@
+// Package po reads and writes gettext PO files, and updates the message catalogs of each
+// language from templates, including merging, obsolete entries, linting and exports.
+package po
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Entry is a single entry read from a PO file
+type Entry struct {
+	TranslatorComments []string // "# comment"
+	ExtractedComments  []string // "#. comment"
+	References         []string // "#: file:line"
+	Flags              []string // "#, flag"
+
+	// Previous strings, as written by msgmerge ("#| msgid ...")
+	PreviousContext string
+	PreviousID      string
+	PreviousPlural  string
+
+	Context  string
+	ID       string
+	IDPlural string
+	Str      []string // msgstr, or msgstr[n] if the entry has a plural form
+	Obsolete bool
+
+	Line int // Line of msgid in the file
+}
+
+// File is the contents of a PO file, including the header entry
+type File struct {
+	Entries []*Entry
+}
+
+// HasFlag checks if flag is set on the entry
+func (e *Entry) HasFlag(flag string) bool {
+	for _, f := range e.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// copy returns a copy of e that doesn't share any slices with it
+func (e *Entry) copy() *Entry {
+	c := *e
+	c.TranslatorComments = append([]string(nil), e.TranslatorComments...)
+	c.ExtractedComments = append([]string(nil), e.ExtractedComments...)
+	c.References = append([]string(nil), e.References...)
+	c.Flags = append([]string(nil), e.Flags...)
+	c.Str = append([]string(nil), e.Str...)
+	return &c
+}
+
+// copy returns a copy of f, with copies of all entries
+func (f *File) copy() *File {
+	c := &File{Entries: make([]*Entry, len(f.Entries))}
+	for i, e := range f.Entries {
+		c.Entries[i] = e.copy()
+	}
+	return c
+}
+
+// removeFlag removes flag from the entry, if it's set
+func (e *Entry) removeFlag(flag string) {
+	n := 0
+	for _, f := range e.Flags {
+		if f != flag {
+			e.Flags[n] = f
+			n++
+		}
+	}
+	e.Flags = e.Flags[:n]
+}
+
+// IsHeader checks if this is the header entry
+func (e *Entry) IsHeader() bool {
+	return e.ID == "" && e.Context == "" && !e.Obsolete
+}
+
+// IsTranslated checks if all forms of the entry have a translation
+func (e *Entry) IsTranslated() bool {
+	if len(e.Str) == 0 {
+		return false
+	}
+	for _, s := range e.Str {
+		if s == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Header returns the header entry, or nil if the file has no header
+func (f *File) Header() *Entry {
+	for _, e := range f.Entries {
+		if e.IsHeader() {
+			return e
+		}
+	}
+	return nil
+}
+
+// HeaderField returns the value of the field name in the header of f, or an empty string if not set
+func (f *File) HeaderField(name string) string {
+	header := f.Header()
+	if header == nil || len(header.Str) == 0 {
+		return ""
+	}
+
+	for _, line := range strings.Split(header.Str[0], "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if found && strings.EqualFold(strings.TrimSpace(key), name) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// PluralCount returns the number of plural forms declared in the header of f, defaulting to 2
+func (f *File) PluralCount() int {
+	for _, part := range strings.Split(f.HeaderField("Plural-Forms"), ";") {
+		key, value, found := strings.Cut(part, "=")
+		if found && strings.TrimSpace(key) == "nplurals" {
+			if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 2
+}
+
+// ReadFile reads and parses the PO file at path
+func ReadFile(path string) (*File, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	f, err := Parse(fd)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+	return f, nil
+}
+
+// Parse parses a PO file
+func Parse(r io.Reader) (*File, error) {
+	f := &File{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+
+	var entry *Entry
+	var target *string // String that continuation lines are appended to
+	var hasStr bool    // The current entry has a msgstr, so any new keyword starts a new entry
+	var lineNo int
+
+	newEntry := func() {
+		entry = &Entry{}
+		f.Entries = append(f.Entries, entry)
+		target = nil
+		hasStr = false
+	}
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			if entry != nil && hasStr {
+				entry = nil
+			}
+			continue
+		}
+
+		obsolete := false
+		if strings.HasPrefix(line, "#~") {
+			obsolete = true
+			line = strings.TrimSpace(line[2:])
+			if strings.HasPrefix(line, "|") {
+				line = "#" + line
+			}
+		}
+
+		// Continuation of the previous string
+		if strings.HasPrefix(line, "\"") {
+			if target == nil {
+				return nil, fmt.Errorf("%d: unexpected string", lineNo)
+			}
+			s, err := unquotePO(line)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", lineNo, err)
+			}
+			*target += s
+			continue
+		}
+
+		isComment := strings.HasPrefix(line, "#")
+		isPrevious := strings.HasPrefix(line, "#|")
+		if entry == nil || (hasStr && (isComment || strings.HasPrefix(line, "msgctxt") || strings.HasPrefix(line, "msgid "))) {
+			newEntry()
+		}
+		entry.Obsolete = entry.Obsolete || obsolete
+
+		if isComment && !isPrevious {
+			target = nil
+			switch {
+			case strings.HasPrefix(line, "#,"):
+				for _, flag := range strings.Split(line[2:], ",") {
+					if flag = strings.TrimSpace(flag); flag != "" {
+						entry.Flags = append(entry.Flags, flag)
+					}
+				}
+			case strings.HasPrefix(line, "#:"):
+				entry.References = append(entry.References, strings.Fields(line[2:])...)
+			case strings.HasPrefix(line, "#."):
+				entry.ExtractedComments = append(entry.ExtractedComments, strings.TrimSpace(line[2:]))
+			default:
+				entry.TranslatorComments = append(entry.TranslatorComments, strings.TrimPrefix(strings.TrimPrefix(line, "#"), " "))
+			}
+			continue
+		}
+
+		if isPrevious {
+			line = strings.TrimSpace(line[2:])
+			if strings.HasPrefix(line, "\"") {
+				if target == nil {
+					return nil, fmt.Errorf("%d: unexpected string", lineNo)
+				}
+				s, err := unquotePO(line)
+				if err != nil {
+					return nil, fmt.Errorf("%d: %w", lineNo, err)
+				}
+				*target += s
+				continue
+			}
+		}
+
+		keyword, value, found := strings.Cut(line, " ")
+		if !found {
+			return nil, fmt.Errorf("%d: invalid line '%s'", lineNo, line)
+		}
+
+		s, err := unquotePO(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", lineNo, err)
+		}
+
+		if isPrevious {
+			switch keyword {
+			case "msgctxt":
+				entry.PreviousContext = s
+				target = &entry.PreviousContext
+			case "msgid":
+				entry.PreviousID = s
+				target = &entry.PreviousID
+			case "msgid_plural":
+				entry.PreviousPlural = s
+				target = &entry.PreviousPlural
+			}
+			continue
+		}
+
+		switch {
+		case keyword == "msgctxt":
+			entry.Context = s
+			target = &entry.Context
+		case keyword == "msgid":
+			entry.ID = s
+			entry.Line = lineNo
+			target = &entry.ID
+		case keyword == "msgid_plural":
+			entry.IDPlural = s
+			target = &entry.IDPlural
+		case keyword == "msgstr":
+			entry.Str = append(entry.Str, s)
+			target = &entry.Str[len(entry.Str)-1]
+			hasStr = true
+		case strings.HasPrefix(keyword, "msgstr["):
+			idx, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(keyword, "msgstr["), "]"))
+			if err != nil || idx != len(entry.Str) {
+				return nil, fmt.Errorf("%d: invalid plural index in '%s'", lineNo, keyword)
+			}
+			entry.Str = append(entry.Str, s)
+			target = &entry.Str[idx]
+			hasStr = true
+		default:
+			return nil, fmt.Errorf("%d: unknown keyword '%s'", lineNo, keyword)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Drop trailing comment-only entries
+	if len(f.Entries) > 0 && f.Entries[len(f.Entries)-1].Str == nil {
+		f.Entries = f.Entries[:len(f.Entries)-1]
+	}
+	return f, nil
+}
+
+// unquotePO decodes a quoted PO string
+func unquotePO(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+
+	var sb strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		c := s[i]
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i >= len(s)-1 {
+			return "", fmt.Errorf("invalid escape sequence in string %s", s)
+		}
+
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'a':
+			sb.WriteByte('\a')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'v':
+			sb.WriteByte('\v')
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String(), nil
+}
+
+// WriteFile writes f to path
+func WriteFile(path string, f *File) error {
+	fd, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create file '%s': %w", path, err)
+	}
+
+	err = f.Write(fd)
+	if err != nil {
+		fd.Close()
+		return fmt.Errorf("could not write file '%s': %w", path, err)
+	}
+	return fd.Close()
+}
+
+// Write writes all entries of f to w in PO format
+func (f *File) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for i, e := range f.Entries {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		e.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (e *Entry) write(w *bufio.Writer) {
+	for _, c := range e.TranslatorComments {
+		if c == "" {
+			w.WriteString("#\n")
+			continue
+		}
+		fmt.Fprintf(w, "# %s\n", c)
+	}
+	for _, c := range e.ExtractedComments {
+		fmt.Fprintf(w, "#. %s\n", c)
+	}
+	if len(e.References) > 0 {
+		fmt.Fprintf(w, "#: %s\n", strings.Join(e.References, " "))
+	}
+	if len(e.Flags) > 0 {
+		fmt.Fprintf(w, "#, %s\n", strings.Join(e.Flags, ", "))
+	}
+
+	prefix := ""
+	if e.Obsolete {
+		prefix = "#~ "
+	}
+
+	previous := "#| "
+	if e.Obsolete {
+		previous = "#~| "
+	}
+	if e.PreviousContext != "" {
+		writePOString(w, previous, "msgctxt", e.PreviousContext)
+	}
+	if e.PreviousID != "" {
+		writePOString(w, previous, "msgid", e.PreviousID)
+	}
+	if e.PreviousPlural != "" {
+		writePOString(w, previous, "msgid_plural", e.PreviousPlural)
+	}
+
+	if e.Context != "" {
+		writePOString(w, prefix, "msgctxt", e.Context)
+	}
+	writePOString(w, prefix, "msgid", e.ID)
+
+	if e.IDPlural == "" {
+		str := ""
+		if len(e.Str) > 0 {
+			str = e.Str[0]
+		}
+		writePOString(w, prefix, "msgstr", str)
+		return
+	}
+
+	writePOString(w, prefix, "msgid_plural", e.IDPlural)
+	strs := e.Str
+	if len(strs) == 0 {
+		strs = []string{"", ""}
+	}
+	for i, str := range strs {
+		writePOString(w, prefix, fmt.Sprintf("msgstr[%d]", i), str)
+	}
+}
+
+// poEscaper escapes strings for use in PO files
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\t", `\t`, "\r", `\r`)
+
+// writePOString writes keyword and s as a quoted PO string. Strings containing
+// newlines are split into one line per row, starting with an empty string.
+func writePOString(w *bufio.Writer, prefix string, keyword string, s string) {
+	rows := strings.SplitAfter(s, "\n")
+	if rows[len(rows)-1] == "" {
+		rows = rows[:len(rows)-1]
+	}
+
+	if len(rows) <= 1 {
+		fmt.Fprintf(w, "%s%s \"%s\"\n", prefix, keyword, strings.ReplaceAll(poEscaper.Replace(s), "\n", `\n`))
+		return
+	}
+
+	fmt.Fprintf(w, "%s%s \"\"\n", prefix, keyword)
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\"%s\"\n", prefix, strings.ReplaceAll(poEscaper.Replace(row), "\n", `\n`))
+	}
+}
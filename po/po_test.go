@@ -0,0 +1,68 @@
+package po
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPOFile(t *testing.T) {
+	cwd, _ := os.Getwd()
+	f, err := ReadFile(filepath.Join(cwd, "testdata", "locales", "sv_SE", "default.po"))
+	require.Nil(t, err)
+	require.Len(t, f.Entries, 10)
+
+	header := f.Header()
+	require.NotNil(t, header)
+	require.Contains(t, header.Str[0], "Plural-Forms: nplurals=2; plural=(n != 1);\n")
+
+	e := f.Entries[2]
+	require.Equal(t, "Hello %s, you have %d messages", e.ID)
+	require.Equal(t, []string{"A translator comment"}, e.TranslatorComments)
+	require.Equal(t, []string{"format.go:7"}, e.References)
+	require.Equal(t, []string{"go-format"}, e.Flags)
+	require.Equal(t, 17, e.Line)
+
+	e = f.Entries[3]
+	require.Equal(t, "%d files", e.IDPlural)
+	require.Equal(t, []string{"En fil", "%d filer"}, e.Str)
+
+	e = f.Entries[6]
+	require.Equal(t, "\nString from blocktrans\n", e.ID)
+
+	e = f.Entries[7]
+	require.True(t, e.HasFlag("fuzzy"))
+	require.Equal(t, "Old %s", e.PreviousID)
+
+	e = f.Entries[8]
+	require.False(t, e.IsTranslated())
+
+	e = f.Entries[9]
+	require.True(t, e.Obsolete)
+	require.Equal(t, "Removed %s", e.ID)
+	require.Equal(t, []string{"Borttagen"}, e.Str)
+}
+
+func TestWritePO(t *testing.T) {
+	cwd, _ := os.Getwd()
+	path := filepath.Join(cwd, "testdata", "locales", "sv_SE", "default.po")
+	f, err := ReadFile(path)
+	require.Nil(t, err)
+
+	buf := &bytes.Buffer{}
+	require.Nil(t, f.Write(buf))
+
+	written, err := Parse(bytes.NewReader(buf.Bytes()))
+	require.Nil(t, err)
+
+	for i := range f.Entries {
+		f.Entries[i].Line = 0
+		written.Entries[i].Line = 0
+	}
+	require.Equal(t, f.Entries, written.Entries)
+	require.Contains(t, buf.String(), "msgid \"\"\n\"\\n\"\n\"String from blocktrans\\n\"\n")
+	require.Contains(t, buf.String(), "#~ msgid \"Removed %s\"\n#~ msgstr \"Borttagen\"\n")
+}
@@ -0,0 +1,107 @@
+package po
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yzzyx/makemessage/internal/format"
+)
+
+// DefaultPseudoLocale is the language commonly used for pseudo-localization
+const DefaultPseudoLocale = "en_XA"
+
+// pseudoAccents replaces ASCII letters with accented versions that are still readable
+var pseudoAccents = strings.NewReplacer(
+	"a", "á", "b", "ƀ", "c", "ç", "d", "ð", "e", "é", "f", "ƒ", "g", "ĝ", "h", "ĥ", "i", "î",
+	"j", "ĵ", "k", "ķ", "l", "ļ", "m", "ɱ", "n", "ñ", "o", "ö", "p", "þ", "q", "ǫ", "r", "ŕ",
+	"s", "š", "t", "ţ", "u", "û", "v", "ṽ", "w", "ŵ", "x", "ẋ", "y", "ý", "z", "ž",
+	"A", "Å", "B", "Ɓ", "C", "Ç", "D", "Ð", "E", "É", "F", "Ƒ", "G", "Ĝ", "H", "Ĥ", "I", "Î",
+	"J", "Ĵ", "K", "Ķ", "L", "Ļ", "M", "Ṁ", "N", "Ñ", "O", "Ö", "P", "Þ", "Q", "Ǫ", "R", "Ŕ",
+	"S", "Š", "T", "Ţ", "U", "Û", "V", "Ṽ", "W", "Ŵ", "X", "Ẋ", "Y", "Ý", "Z", "Ž",
+)
+
+// Pseudolocalize returns a pseudo-translation of s, used to find hard-coded strings and layouts
+// that break with longer translations. Letters are replaced with accented versions, the text is
+// padded to be about 40% longer, and the result is wrapped in brackets, e.g. "Save %d files" becomes
+// "[Šávé %d ƒîļéš ~~~~]". Placeholders, template variables, HTML tags and entities are kept as is.
+func Pseudolocalize(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("[")
+
+	letters, prev := 0, 0
+	for _, loc := range format.Placeholders(s) {
+		letters += utf8.RuneCountInString(s[prev:loc[0]])
+		b.WriteString(pseudoAccents.Replace(s[prev:loc[0]]))
+		b.WriteString(s[loc[0]:loc[1]])
+		prev = loc[1]
+	}
+	letters += utf8.RuneCountInString(s[prev:])
+	b.WriteString(pseudoAccents.Replace(s[prev:]))
+
+	// Short strings grow relatively more when translated
+	padding := max(1, letters*4/10)
+	if letters < 10 {
+		padding = max(1, letters*8/10)
+	}
+	b.WriteString(" ")
+	b.WriteString(strings.Repeat("~", padding))
+	b.WriteString("]")
+	return b.String()
+}
+
+// setHeaderField returns the header h with the field name set to value
+func setHeaderField(h string, name string, value string) string {
+	lines := strings.SplitAfter(h, "\n")
+	for i, line := range lines {
+		key, _, found := strings.Cut(line, ":")
+		if found && strings.EqualFold(strings.TrimSpace(key), name) {
+			lines[i] = name + ": " + value + "\n"
+			return strings.Join(lines, "")
+		}
+	}
+
+	if h != "" && !strings.HasSuffix(h, "\n") {
+		h += "\n"
+	}
+	return h + name + ": " + value + "\n"
+}
+
+// PseudoFile returns a message file for the pseudo-locale lang, with every entry in
+// template translated by Pseudolocalize. Plural forms use the pseudo-translation of the
+// msgid for the first form, and of msgid_plural for the others. The entries are copies,
+// so the returned file can be changed without changing template.
+func PseudoFile(template *File, lang string) *File {
+	f := &File{}
+	nplurals := template.PluralCount()
+
+	if header := template.Header(); header != nil {
+		h := header.copy()
+		h.Str = []string{setHeaderField(strings.Join(header.Str, ""), "Language", lang)}
+		h.removeFlag("fuzzy")
+		f.Entries = append(f.Entries, h)
+	}
+
+	for _, t := range template.Entries {
+		if t.IsHeader() {
+			continue
+		}
+
+		// The pseudo-translations are complete, and don't need to be reviewed
+		e := t.copy()
+		e.removeFlag("fuzzy")
+		e.Str = []string{Pseudolocalize(t.ID)}
+		if t.IDPlural != "" {
+			e.Str = make([]string, nplurals)
+			for i := range e.Str {
+				e.Str[i] = Pseudolocalize(t.IDPlural)
+			}
+			e.Str[0] = Pseudolocalize(t.ID)
+		}
+		f.Entries = append(f.Entries, e)
+	}
+	return f
+}
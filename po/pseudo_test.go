@@ -0,0 +1,50 @@
+package po
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPseudolocalize(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"", ""},
+		{"Save", "[Šáṽé ~~~]"},
+		{"Save %d files", "[Šáṽé %d ƒîļéš ~~~~]"},
+		{"100%% of %[1]s", "[100%% öƒ %[1]s ~~~~~]"},
+		{"Hello {{ user.name }}, <a href=\"/profile\">profile</a>", "[Ĥéļļö {{ user.name }}, <a href=\"/profile\">þŕöƒîļé</a> ~~~~~~]"},
+		{"%(count)s items &amp; more", "[%(count)s îţéɱš &amp; ɱöŕé ~~~~]"},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.out, Pseudolocalize(test.in), test.in)
+	}
+}
+
+func TestPseudoFile(t *testing.T) {
+	template := NewTemplate()
+	template.Entries = append(template.Entries,
+		&Entry{References: []string{"main.go:10"}, ID: "Save"},
+		&Entry{ID: "%d file", IDPlural: "%d files", Flags: []string{"fuzzy", "go-format"}},
+	)
+
+	f := PseudoFile(template, DefaultPseudoLocale)
+	require.Len(t, f.Entries, 3)
+	require.Equal(t, "en_XA", f.HeaderField("Language"))
+	require.False(t, f.Header().HasFlag("fuzzy"))
+	require.Equal(t, []string{"main.go:10"}, f.Entries[1].References)
+	require.Equal(t, []string{"[Šáṽé ~~~]"}, f.Entries[1].Str)
+	require.Equal(t, []string{"[%d ƒîļé ~~~~]", "[%d ƒîļéš ~~~~]"}, f.Entries[2].Str)
+	require.Equal(t, []string{"go-format"}, f.Entries[2].Flags)
+
+	// The template is not modified, and doesn't share any slices with the pseudo file
+	f.Entries[1].References[0] = "other.go:1"
+	f.Entries[2].Flags = append(f.Entries[2].Flags, "no-c-format")
+	require.Nil(t, template.Entries[1].Str)
+	require.Equal(t, []string{"main.go:10"}, template.Entries[1].References)
+	require.Equal(t, []string{"fuzzy", "go-format"}, template.Entries[2].Flags)
+	require.True(t, template.Header().HasFlag("fuzzy"))
+}
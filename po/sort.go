@@ -0,0 +1,102 @@
+package po
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Sort orders for the entries of a message file
+const (
+	SortByLocation = "location" // By the first reference of each entry, comparing line numbers numerically
+	SortByMsgID    = "msgid"    // By msgid, then context
+	SortByExisting = "existing" // In the order of the existing message file, with new entries last
+)
+
+// compareReferences compares two references by file, line and column
+func compareReferences(a, b string) int {
+	fileA, lineA, colA := splitReference(a)
+	fileB, lineB, colB := splitReference(b)
+	switch {
+	case fileA != fileB:
+		if fileA < fileB {
+			return -1
+		}
+		return 1
+	case lineA != lineB:
+		return lineA - lineB
+	}
+	return colA - colB
+}
+
+// firstReference returns the reference of e that sorts first, or "" if e has no references
+func firstReference(e *Entry) string {
+	var first string
+	for i, ref := range e.References {
+		if i == 0 || compareReferences(ref, first) < 0 {
+			first = ref
+		}
+	}
+	return first
+}
+
+// lessByMsgID orders entries by msgid, context and msgid_plural
+func lessByMsgID(a, b *Entry) bool {
+	switch {
+	case a.ID != b.ID:
+		return a.ID < b.ID
+	case a.Context != b.Context:
+		return a.Context < b.Context
+	}
+	return a.IDPlural < b.IDPlural
+}
+
+// Sort sorts the entries of f by SortByLocation or SortByMsgID. The header entry is kept first.
+func (f *File) Sort(order string) error {
+	entries := f.Entries
+	if len(entries) > 0 && entries[0].IsHeader() {
+		entries = entries[1:]
+	}
+
+	switch order {
+	case SortByLocation:
+		sort.SliceStable(entries, func(i, j int) bool {
+			if c := compareReferences(firstReference(entries[i]), firstReference(entries[j])); c != 0 {
+				return c < 0
+			}
+			return lessByMsgID(entries[i], entries[j])
+		})
+	case SortByMsgID:
+		sort.SliceStable(entries, func(i, j int) bool {
+			return lessByMsgID(entries[i], entries[j])
+		})
+	default:
+		return fmt.Errorf("unknown sort order '%s', expected %s, %s or %s", order, SortByLocation, SortByMsgID, SortByExisting)
+	}
+	return nil
+}
+
+// SortLike sorts the entries of f in the same order as in existing. Entries not found in
+// existing are placed last, in their current order. The header entry is kept first.
+func (f *File) SortLike(existing *File) {
+	index := map[string]int{}
+	for i, e := range existing.Entries {
+		index[e.Context+contextSeparator+e.ID] = i
+	}
+
+	entries := f.Entries
+	if len(entries) > 0 && entries[0].IsHeader() {
+		entries = entries[1:]
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		idxA, okA := index[entries[i].Context+contextSeparator+entries[i].ID]
+		idxB, okB := index[entries[j].Context+contextSeparator+entries[j].ID]
+		switch {
+		case okA && okB:
+			return idxA < idxB
+		case okA != okB:
+			return okA
+		}
+		return false
+	})
+}
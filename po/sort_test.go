@@ -0,0 +1,46 @@
+package po
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func entryIDs(f *File) []string {
+	var ids []string
+	for _, e := range f.Entries {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestSort(t *testing.T) {
+	f := NewTemplate()
+	f.Entries = append(f.Entries,
+		&Entry{ID: "b", References: []string{"main.go:100"}},
+		&Entry{ID: "a", References: []string{"main.go:20", "admin.go:5"}},
+		&Entry{ID: "c", References: []string{"main.go:9"}},
+		&Entry{ID: "a", Context: "menu", References: []string{"main.go:9"}},
+	)
+
+	require.Nil(t, f.Sort(SortByLocation))
+	require.Equal(t, []string{"", "a", "a", "c", "b"}, entryIDs(f))
+	require.Equal(t, "menu", f.Entries[2].Context)
+
+	require.Nil(t, f.Sort(SortByMsgID))
+	require.Equal(t, []string{"", "a", "a", "b", "c"}, entryIDs(f))
+	require.Equal(t, "", f.Entries[1].Context)
+
+	require.NotNil(t, f.Sort("random"))
+}
+
+func TestSortLike(t *testing.T) {
+	existing := NewTemplate()
+	existing.Entries = append(existing.Entries, &Entry{ID: "c"}, &Entry{ID: "a"}, &Entry{ID: "removed"})
+
+	f := NewTemplate()
+	f.Entries = append(f.Entries, &Entry{ID: "a"}, &Entry{ID: "new"}, &Entry{ID: "c"}, &Entry{ID: "b"})
+
+	f.SortLike(existing)
+	require.Equal(t, []string{"", "c", "a", "new", "b"}, entryIDs(f))
+}
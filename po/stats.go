@@ -0,0 +1,105 @@
+package po
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Stats holds the translation statistics of a single PO file
+type Stats struct {
+	Language string `json:"language"`
+	Domain   string `json:"domain"`
+
+	Translated   int `json:"translated"`
+	Fuzzy        int `json:"fuzzy"`
+	Untranslated int `json:"untranslated"`
+	Obsolete     int `json:"obsolete"`
+
+	// Number of words in the msgids of each category
+	TranslatedWords   int `json:"translated_words"`
+	FuzzyWords        int `json:"fuzzy_words"`
+	UntranslatedWords int `json:"untranslated_words"`
+
+	Coverage float64 `json:"coverage"` // Percentage of translated entries, excluding obsolete entries
+}
+
+// CollectStats reads the PO files for the given languages in outputFolder, and counts their entries.
+// A language without any PO files gets a single row without a domain, and with a coverage of 0.
+func CollectStats(outputFolder string, languages []string) ([]Stats, error) {
+	catalogs, err := readCatalogs(outputFolder, languages)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []Stats
+	for _, lang := range languages {
+		found := false
+		for _, c := range catalogs {
+			if c.Language == lang {
+				stats = append(stats, fileStats(lang, c.Domain, c.File))
+				found = true
+			}
+		}
+
+		if !found {
+			stats = append(stats, Stats{Language: lang})
+		}
+	}
+	return stats, nil
+}
+
+// fileStats counts the entries of the PO file f
+func fileStats(lang string, domain string, f *File) Stats {
+	s := Stats{
+		Language: lang,
+		Domain:   domain,
+	}
+
+	for _, e := range f.Entries {
+		words := len(strings.Fields(e.ID))
+		switch {
+		case e.IsHeader():
+		case e.Obsolete:
+			s.Obsolete++
+		case e.HasFlag("fuzzy"):
+			s.Fuzzy++
+			s.FuzzyWords += words
+		case e.IsTranslated():
+			s.Translated++
+			s.TranslatedWords += words
+		default:
+			s.Untranslated++
+			s.UntranslatedWords += words
+		}
+	}
+
+	s.Coverage = 100
+	if total := s.Translated + s.Fuzzy + s.Untranslated; total > 0 {
+		s.Coverage = float64(s.Translated) * 100 / float64(total)
+	}
+	return s
+}
+
+// WriteStats writes stats to w, either as a table or as JSON
+func WriteStats(w io.Writer, stats []Stats, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "LANGUAGE\tDOMAIN\tTRANSLATED\tFUZZY\tUNTRANSLATED\tOBSOLETE\tWORDS\tCOVERAGE\t")
+		for _, s := range stats {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d/%d\t%.1f%%\t\n",
+				s.Language, s.Domain, s.Translated, s.Fuzzy, s.Untranslated, s.Obsolete,
+				s.TranslatedWords, s.TranslatedWords+s.FuzzyWords+s.UntranslatedWords, s.Coverage)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown format '%s'", format)
+	}
+}
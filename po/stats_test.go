@@ -0,0 +1,43 @@
+package po
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectStats(t *testing.T) {
+	cwd, _ := os.Getwd()
+	stats, err := CollectStats(filepath.Join(cwd, "testdata", "locales"), []string{"sv_SE"})
+	require.Nil(t, err)
+	require.Len(t, stats, 1)
+
+	s := stats[0]
+	require.Equal(t, "sv_SE", s.Language)
+	require.Equal(t, "default", s.Domain)
+	require.Equal(t, 6, s.Translated)
+	require.Equal(t, 1, s.Fuzzy)
+	require.Equal(t, 1, s.Untranslated)
+	require.Equal(t, 1, s.Obsolete)
+	require.Equal(t, 2, s.FuzzyWords)
+	require.Equal(t, 2, s.UntranslatedWords)
+	require.InDelta(t, 75.0, s.Coverage, 0.01)
+
+	buf := &bytes.Buffer{}
+	require.Nil(t, WriteStats(buf, stats, "json"))
+	require.Contains(t, buf.String(), `"untranslated": 1`)
+
+	buf.Reset()
+	require.Nil(t, WriteStats(buf, stats, "table"))
+	require.Contains(t, buf.String(), "75.0%")
+
+	require.NotNil(t, WriteStats(buf, stats, "xml"))
+
+	stats, err = CollectStats(filepath.Join(cwd, "testdata", "locales"), []string{"sv_SE", "de_DE"})
+	require.Nil(t, err)
+	require.Len(t, stats, 2)
+	require.Equal(t, Stats{Language: "de_DE"}, stats[1])
+}
@@ -0,0 +1,221 @@
+package po
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TMEntry is a single translation in a translation memory
+type TMEntry struct {
+	Context  string   `json:"context,omitempty"`
+	ID       string   `json:"msgid"`
+	IDPlural string   `json:"msgid_plural,omitempty"`
+	Str      []string `json:"msgstr"`
+	Source   string   `json:"source"` // Path of the message file the translation was read from
+}
+
+// TranslationMemory holds translations from any number of message files, by language,
+// to be reused when the same or similar strings are found elsewhere
+type TranslationMemory struct {
+	Languages map[string][]TMEntry `json:"languages"`
+
+	index     map[string]map[string]*TMEntry // Entries by language and context+msgid
+	indexOnce sync.Once                      // The index is built on first use, which may be from several goroutines
+}
+
+// Reused describes a translation taken from a translation memory
+type Reused struct {
+	Path   string // Path of the message file the translation was added to
+	Entry  *Entry
+	Source string // Path of the message file the translation was originally read from
+	Fuzzy  bool   // The translation was for a similar string, and needs to be reviewed
+}
+
+func (r Reused) String() string {
+	kind := "translation"
+	if r.Fuzzy {
+		kind = "fuzzy translation"
+	}
+	return fmt.Sprintf("%s: reused %s of msgid %q from %s", r.Path, kind, r.Entry.ID, r.Source)
+}
+
+// fileLanguage returns the language of the message file at path, from its header, or from the directory
+// it's in, as in "locales/sv_SE/default.po" or "locale/sv/LC_MESSAGES/django.po"
+func fileLanguage(path string, f *File) string {
+	if lang := f.HeaderField("Language"); lang != "" {
+		return lang
+	}
+
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) == "LC_MESSAGES" {
+		dir = filepath.Dir(dir)
+	}
+	return filepath.Base(dir)
+}
+
+// BuildTranslationMemory reads the translations in the message files at paths. Directories are searched
+// recursively for .po files. Fuzzy and obsolete entries are ignored, and if the same string is translated
+// in several files, the first one found is used. If languages is not empty, only those languages are included.
+func BuildTranslationMemory(paths []string, languages []string) (*TranslationMemory, error) {
+	var files []string
+	for _, p := range paths {
+		err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() && filepath.Ext(path) == ".po" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+
+	tm := &TranslationMemory{Languages: map[string][]TMEntry{}}
+	seen := map[string]bool{}
+	for _, path := range files {
+		f, err := ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		lang := fileLanguage(path, f)
+		if len(languages) > 0 && !containsString(languages, lang) {
+			continue
+		}
+
+		for _, e := range f.Entries {
+			key := lang + "\x00" + e.Context + contextSeparator + e.ID
+			if e.IsHeader() || e.Obsolete || e.HasFlag("fuzzy") || !e.IsTranslated() || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			tm.Languages[lang] = append(tm.Languages[lang], TMEntry{
+				Context:  e.Context,
+				ID:       e.ID,
+				IDPlural: e.IDPlural,
+				Str:      e.Str,
+				Source:   path,
+			})
+		}
+	}
+	return tm, nil
+}
+
+// containsString checks if list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadTranslationMemory reads a translation memory written by WriteTranslationMemory
+func ReadTranslationMemory(path string) (*TranslationMemory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tm := &TranslationMemory{}
+	err = json.Unmarshal(data, tm)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse translation memory '%s': %w", path, err)
+	}
+	return tm, nil
+}
+
+// WriteTranslationMemory writes tm to path as JSON
+func WriteTranslationMemory(path string, tm *TranslationMemory) error {
+	data, err := json.MarshalIndent(tm, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// entries returns the translations for lang. If there are none, the translations
+// for the base language are used, e.g. "sv" for "sv_SE".
+func (tm *TranslationMemory) entries(lang string) (string, []TMEntry) {
+	if entries, ok := tm.Languages[lang]; ok {
+		return lang, entries
+	}
+
+	base, _, _ := strings.Cut(strings.ReplaceAll(lang, "-", "_"), "_")
+	return base, tm.Languages[base]
+}
+
+// lookup returns the translation of the string in e, with the same context and msgid, or nil
+func (tm *TranslationMemory) lookup(lang string, e *Entry) *TMEntry {
+	tm.indexOnce.Do(func() {
+		tm.index = map[string]map[string]*TMEntry{}
+		for l, entries := range tm.Languages {
+			tm.index[l] = map[string]*TMEntry{}
+			for i := range entries {
+				tm.index[l][entries[i].Context+contextSeparator+entries[i].ID] = &entries[i]
+			}
+		}
+	})
+
+	lang, _ = tm.entries(lang)
+	return tm.index[lang][e.Context+contextSeparator+e.ID]
+}
+
+// Apply fills in the untranslated entries in f, the message file for lang, with translations from tm.
+// Strings with the same context, msgid and msgid_plural are translated as is. With fuzzy matching,
+// other strings are translated using the most similar string in tm, and marked as fuzzy.
+func (tm *TranslationMemory) Apply(f *File, lang string, opts MergeOptions) []Reused {
+	threshold := opts.FuzzyThreshold
+	if threshold <= 0 {
+		threshold = DefaultFuzzyThreshold
+	}
+	nplurals := f.PluralCount()
+
+	var candidates []*Entry
+	sources := map[*Entry]string{}
+	if opts.FuzzyMatching {
+		_, entries := tm.entries(lang)
+		for _, t := range entries {
+			c := &Entry{Context: t.Context, ID: t.ID, IDPlural: t.IDPlural, Str: t.Str}
+			candidates = append(candidates, c)
+			sources[c] = t.Source
+		}
+	}
+
+	var reused []Reused
+	for _, e := range f.Entries {
+		if e.IsHeader() || e.Obsolete || hasTranslation(e) {
+			continue
+		}
+
+		count := 1
+		if e.IDPlural != "" {
+			count = nplurals
+		}
+
+		if t := tm.lookup(lang, e); t != nil && t.IDPlural == e.IDPlural && len(t.Str) == count {
+			e.Str = append([]string(nil), t.Str...)
+			reused = append(reused, Reused{Entry: e, Source: t.Source})
+			continue
+		}
+
+		if match := fuzzyMatch(e, candidates, threshold); match != nil {
+			e.Str = pluralStrings(match.Str, e.IDPlural != "", nplurals)
+			e.PreviousContext, e.PreviousID, e.PreviousPlural = match.Context, match.ID, match.IDPlural
+			e.Flags = append(e.Flags, "fuzzy")
+			reused = append(reused, Reused{Entry: e, Source: sources[match], Fuzzy: true})
+		}
+	}
+	return reused
+}
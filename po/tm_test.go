@@ -0,0 +1,76 @@
+package po
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslationMemory(t *testing.T) {
+	dir := t.TempDir()
+
+	other := filepath.Join(dir, "other", "locale", "sv", "LC_MESSAGES", "django.po")
+	require.Nil(t, os.MkdirAll(filepath.Dir(other), 0755))
+	require.Nil(t, os.WriteFile(other, []byte(`msgid ""
+msgstr ""
+"Language: sv\n"
+
+msgid "Sign in"
+msgstr "Logga in"
+
+msgid "Delete the selected file"
+msgstr "Ta bort den valda filen"
+
+#, fuzzy
+msgid "Cancel"
+msgstr "Avbryt"
+`), 0644))
+
+	local := filepath.Join(dir, "locales", "sv_SE", "admin.po")
+	require.Nil(t, os.MkdirAll(filepath.Dir(local), 0755))
+	require.Nil(t, os.WriteFile(local, []byte(`msgid "Sign in"
+msgstr "Logga in här"
+
+msgid "Save"
+msgstr "Spara"
+`), 0644))
+
+	tm, err := BuildTranslationMemory([]string{dir}, nil)
+	require.Nil(t, err)
+	require.Len(t, tm.Languages["sv"], 2)
+	require.Len(t, tm.Languages["sv_SE"], 2)
+
+	tm, err = BuildTranslationMemory([]string{dir}, []string{"sv"})
+	require.Nil(t, err)
+	require.Len(t, tm.Languages, 1)
+
+	tmPath := filepath.Join(dir, "tm.json")
+	require.Nil(t, WriteTranslationMemory(tmPath, tm))
+	tm, err = ReadTranslationMemory(tmPath)
+	require.Nil(t, err)
+
+	f := NewTemplate()
+	f.Entries = append(f.Entries,
+		&Entry{ID: "Sign in"},
+		&Entry{ID: "Delete the selected files"},
+		&Entry{ID: "Cancel"},
+		&Entry{ID: "Save", Str: []string{"Spara"}},
+	)
+
+	// Translations for "sv" are used for "sv_SE"
+	reused := tm.Apply(f, "sv_SE", MergeOptions{FuzzyMatching: true})
+	require.Len(t, reused, 2)
+
+	require.Equal(t, []string{"Logga in"}, f.Entries[1].Str)
+	require.False(t, reused[0].Fuzzy)
+	require.Equal(t, other, reused[0].Source)
+
+	require.Equal(t, []string{"Ta bort den valda filen"}, f.Entries[2].Str)
+	require.True(t, f.Entries[2].HasFlag("fuzzy"))
+	require.Equal(t, "Delete the selected file", f.Entries[2].PreviousID)
+	require.True(t, reused[1].Fuzzy)
+
+	require.Empty(t, f.Entries[3].Str)
+}
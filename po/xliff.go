@@ -0,0 +1,514 @@
+package po
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// XLIFF 1.2 document structure
+type xliff12 struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+	Version string      `xml:"version,attr"`
+	File    xliff12File `xml:"file"`
+}
+
+type xliff12File struct {
+	Original       string         `xml:"original,attr"`
+	SourceLanguage string         `xml:"source-language,attr"`
+	TargetLanguage string         `xml:"target-language,attr"`
+	Datatype       string         `xml:"datatype,attr"`
+	Units          []xliff12Unit  `xml:"body>trans-unit"`
+	Groups         []xliff12Group `xml:"body>group"`
+}
+
+type xliff12Group struct {
+	ID      string        `xml:"id,attr"`
+	Restype string        `xml:"restype,attr"`
+	Units   []xliff12Unit `xml:"trans-unit"`
+}
+
+type xliff12Unit struct {
+	ID            string                `xml:"id,attr"`
+	Approved      string                `xml:"approved,attr,omitempty"`
+	Space         string                `xml:"xml:space,attr,omitempty"`
+	Source        string                `xml:"source"`
+	Target        *xliff12Target        `xml:"target"`
+	ContextGroups []xliff12ContextGroup `xml:"context-group"`
+	Notes         []xliff12Note         `xml:"note"`
+}
+
+type xliff12Target struct {
+	State string `xml:"state,attr,omitempty"`
+	Text  string `xml:",chardata"`
+}
+
+type xliff12ContextGroup struct {
+	Name     string           `xml:"name,attr"`
+	Purpose  string           `xml:"purpose,attr"`
+	Contexts []xliff12Context `xml:"context"`
+}
+
+type xliff12Context struct {
+	Type string `xml:"context-type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type xliff12Note struct {
+	From string `xml:"from,attr,omitempty"`
+	Text string `xml:",chardata"`
+}
+
+// XLIFF 2.0 document structure
+type xliff20 struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string      `xml:"version,attr"`
+	SrcLang string      `xml:"srcLang,attr"`
+	TrgLang string      `xml:"trgLang,attr"`
+	File    xliff20File `xml:"file"`
+}
+
+type xliff20File struct {
+	ID     string         `xml:"id,attr"`
+	Units  []xliff20Unit  `xml:"unit"`
+	Groups []xliff20Group `xml:"group"`
+}
+
+type xliff20Group struct {
+	ID    string        `xml:"id,attr"`
+	Type  string        `xml:"type,attr"`
+	Units []xliff20Unit `xml:"unit"`
+}
+
+type xliff20Unit struct {
+	ID      string         `xml:"id,attr"`
+	Notes   []xliff20Note  `xml:"notes>note"`
+	Segment xliff20Segment `xml:"segment"`
+}
+
+type xliff20Note struct {
+	Category string `xml:"category,attr,omitempty"`
+	Text     string `xml:",chardata"`
+}
+
+type xliff20Segment struct {
+	State    string  `xml:"state,attr,omitempty"`
+	SubState string  `xml:"subState,attr,omitempty"`
+	Source   string  `xml:"source"`
+	Target   *string `xml:"target"`
+}
+
+const (
+	xliffPluralType   = "x-gettext-plurals"
+	xliff20FuzzyState = "x-gettext:fuzzy"
+)
+
+// xliffUnitID returns a stable identifier for e, based on its context and msgid
+func xliffUnitID(e *Entry) string {
+	h := sha1.Sum([]byte(e.Context + contextSeparator + e.ID))
+	return hex.EncodeToString(h[:8])
+}
+
+// XLIFFUnit is a single translatable string, independent of XLIFF version.
+// Entries with plural forms are split into one unit per form.
+type XLIFFUnit struct {
+	ID         string
+	Context    string
+	Source     string
+	Target     string
+	HasTarget  bool
+	Fuzzy      bool
+	References []string
+	Translator []string
+	Developer  []string
+}
+
+// xliffUnits splits e into units, one per plural form
+func xliffUnits(e *Entry, nplurals int) []XLIFFUnit {
+	base := XLIFFUnit{
+		ID:         xliffUnitID(e),
+		Context:    e.Context,
+		Fuzzy:      e.HasFlag("fuzzy"),
+		References: e.References,
+		Translator: e.TranslatorComments,
+		Developer:  e.ExtractedComments,
+	}
+
+	if e.IDPlural == "" {
+		base.Source = e.ID
+		if len(e.Str) > 0 && e.Str[0] != "" {
+			base.Target = e.Str[0]
+			base.HasTarget = true
+		}
+		return []XLIFFUnit{base}
+	}
+
+	count := len(e.Str)
+	if count < nplurals {
+		count = nplurals
+	}
+
+	units := make([]XLIFFUnit, count)
+	for i := range units {
+		units[i] = base
+		units[i].ID = fmt.Sprintf("%s[%d]", base.ID, i)
+		units[i].Source = e.IDPlural
+		if i == 0 {
+			units[i].Source = e.ID
+		}
+		if i < len(e.Str) && e.Str[i] != "" {
+			units[i].Target = e.Str[i]
+			units[i].HasTarget = true
+		}
+	}
+	return units
+}
+
+func (u XLIFFUnit) xliff12() xliff12Unit {
+	x := xliff12Unit{
+		ID:     u.ID,
+		Space:  "preserve",
+		Source: u.Source,
+	}
+
+	if u.HasTarget {
+		x.Approved = "yes"
+		x.Target = &xliff12Target{State: "translated", Text: u.Target}
+		if u.Fuzzy {
+			x.Approved = "no"
+			x.Target.State = "needs-review-translation"
+		}
+	}
+
+	if u.Context != "" {
+		x.ContextGroups = append(x.ContextGroups, xliff12ContextGroup{
+			Name:     "po-entry",
+			Purpose:  "information",
+			Contexts: []xliff12Context{{Type: "x-po-msgctxt", Text: u.Context}},
+		})
+	}
+
+	for _, ref := range u.References {
+		group := xliff12ContextGroup{Name: "po-reference", Purpose: "location"}
+		// XLIFF 1.2 has no context type for columns, so they are left out
+		file, line, _ := splitReference(ref)
+		group.Contexts = append(group.Contexts, xliff12Context{Type: "sourcefile", Text: file})
+		if line > 0 {
+			group.Contexts = append(group.Contexts, xliff12Context{Type: "linenumber", Text: strconv.Itoa(line)})
+		}
+		x.ContextGroups = append(x.ContextGroups, group)
+	}
+
+	for _, c := range u.Translator {
+		x.Notes = append(x.Notes, xliff12Note{From: "translator", Text: c})
+	}
+	for _, c := range u.Developer {
+		x.Notes = append(x.Notes, xliff12Note{From: "developer", Text: c})
+	}
+	return x
+}
+
+func (u XLIFFUnit) xliff20() xliff20Unit {
+	x := xliff20Unit{
+		ID:      u.ID,
+		Segment: xliff20Segment{State: "initial", Source: u.Source},
+	}
+
+	if u.HasTarget {
+		target := u.Target
+		x.Segment.Target = &target
+		x.Segment.State = "translated"
+		if u.Fuzzy {
+			x.Segment.SubState = xliff20FuzzyState
+		}
+	}
+
+	if u.Context != "" {
+		x.Notes = append(x.Notes, xliff20Note{Category: "x-po-msgctxt", Text: u.Context})
+	}
+	for _, ref := range u.References {
+		x.Notes = append(x.Notes, xliff20Note{Category: "location", Text: ref})
+	}
+	for _, c := range u.Translator {
+		x.Notes = append(x.Notes, xliff20Note{Category: "translator", Text: c})
+	}
+	for _, c := range u.Developer {
+		x.Notes = append(x.Notes, xliff20Note{Category: "developer", Text: c})
+	}
+	return x
+}
+
+// ExportXLIFF converts all non-obsolete entries of f to an XLIFF document of the given version
+func ExportXLIFF(f *File, version string, sourceLang string, lang string, domain string) (interface{}, error) {
+	nplurals := f.PluralCount()
+
+	switch version {
+	case "1.2":
+		doc := &xliff12{
+			Version: "1.2",
+			File: xliff12File{
+				Original:       domain + ".po",
+				SourceLanguage: sourceLang,
+				TargetLanguage: lang,
+				Datatype:       "po",
+			},
+		}
+
+		for _, e := range f.Entries {
+			if e.IsHeader() || e.Obsolete {
+				continue
+			}
+
+			units := xliffUnits(e, nplurals)
+			if e.IDPlural == "" {
+				doc.File.Units = append(doc.File.Units, units[0].xliff12())
+				continue
+			}
+
+			group := xliff12Group{ID: xliffUnitID(e), Restype: xliffPluralType}
+			for _, u := range units {
+				group.Units = append(group.Units, u.xliff12())
+			}
+			doc.File.Groups = append(doc.File.Groups, group)
+		}
+		return doc, nil
+	case "2.0":
+		doc := &xliff20{
+			Version: "2.0",
+			SrcLang: sourceLang,
+			TrgLang: lang,
+			File:    xliff20File{ID: domain},
+		}
+
+		for _, e := range f.Entries {
+			if e.IsHeader() || e.Obsolete {
+				continue
+			}
+
+			units := xliffUnits(e, nplurals)
+			if e.IDPlural == "" {
+				doc.File.Units = append(doc.File.Units, units[0].xliff20())
+				continue
+			}
+
+			group := xliff20Group{ID: xliffUnitID(e), Type: xliffPluralType}
+			for _, u := range units {
+				group.Units = append(group.Units, u.xliff20())
+			}
+			doc.File.Groups = append(doc.File.Groups, group)
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unknown XLIFF version '%s'", version)
+	}
+}
+
+// ParseXLIFF reads the translated units from an XLIFF 1.2 or 2.0 document
+func ParseXLIFF(b []byte) ([]XLIFFUnit, error) {
+	var root struct {
+		Version string `xml:"version,attr"`
+	}
+	err := xml.Unmarshal(b, &root)
+	if err != nil {
+		return nil, err
+	}
+
+	var units []XLIFFUnit
+	switch root.Version {
+	case "1.2":
+		doc := &xliff12{}
+		err = xml.Unmarshal(b, doc)
+		if err != nil {
+			return nil, err
+		}
+
+		all := doc.File.Units
+		for _, g := range doc.File.Groups {
+			all = append(all, g.Units...)
+		}
+
+		for _, x := range all {
+			u := XLIFFUnit{ID: x.ID, Source: x.Source}
+			if x.Target != nil {
+				u.Target = x.Target.Text
+				u.HasTarget = x.Target.Text != ""
+				u.Fuzzy = x.Approved == "no" || x.Target.State == "new" || strings.HasPrefix(x.Target.State, "needs-")
+			}
+			units = append(units, u)
+		}
+	case "2.0":
+		doc := &xliff20{}
+		err = xml.Unmarshal(b, doc)
+		if err != nil {
+			return nil, err
+		}
+
+		all := doc.File.Units
+		for _, g := range doc.File.Groups {
+			all = append(all, g.Units...)
+		}
+
+		for _, x := range all {
+			u := XLIFFUnit{ID: x.ID, Source: x.Segment.Source}
+			if x.Segment.Target != nil {
+				u.Target = *x.Segment.Target
+				u.HasTarget = u.Target != ""
+				u.Fuzzy = x.Segment.State == "initial" || x.Segment.SubState == xliff20FuzzyState
+			}
+			units = append(units, u)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported XLIFF version '%s'", root.Version)
+	}
+	return units, nil
+}
+
+// ImportXLIFF applies the translations in units to f, and returns a list of conflicts.
+// A unit conflicts if it has no matching entry in f, or if its entry already has a
+// different translation that is not fuzzy. Conflicting translations are only applied
+// if overwrite is set.
+func ImportXLIFF(f *File, units []XLIFFUnit, overwrite bool) (conflicts []string) {
+	type form struct {
+		entry *Entry
+		idx   int
+	}
+
+	forms := map[string]form{}
+	nplurals := f.PluralCount()
+	for _, e := range f.Entries {
+		if e.IsHeader() || e.Obsolete {
+			continue
+		}
+		for i, u := range xliffUnits(e, nplurals) {
+			forms[u.ID] = form{entry: e, idx: i}
+		}
+	}
+
+	// Fuzzy state is kept per entry, so an entry is fuzzy if any of its imported forms are
+	fuzzy := map[*Entry]bool{}
+	imported := map[*Entry]bool{}
+
+	for _, u := range units {
+		if !u.HasTarget {
+			continue
+		}
+
+		fm, ok := forms[u.ID]
+		if !ok {
+			conflicts = append(conflicts, fmt.Sprintf("unit %s (%q) has no matching entry", u.ID, u.Source))
+			continue
+		}
+
+		e := fm.entry
+		for len(e.Str) <= fm.idx {
+			e.Str = append(e.Str, "")
+		}
+
+		current := e.Str[fm.idx]
+		if current != "" && current != u.Target && !e.HasFlag("fuzzy") {
+			conflicts = append(conflicts, fmt.Sprintf("line %d: %q is already translated as %q, not %q", e.Line, e.ID, current, u.Target))
+			if !overwrite {
+				continue
+			}
+		}
+
+		e.Str[fm.idx] = u.Target
+		imported[e] = true
+		fuzzy[e] = fuzzy[e] || u.Fuzzy
+	}
+
+	for e := range imported {
+		flags := e.Flags[:0:0]
+		for _, flag := range e.Flags {
+			if flag != "fuzzy" {
+				flags = append(flags, flag)
+			}
+		}
+		if fuzzy[e] {
+			flags = append([]string{"fuzzy"}, flags...)
+		}
+		e.Flags = flags
+	}
+	return conflicts
+}
+
+// ExportXLIFFFiles converts all PO files of the given languages in outputFolder to XLIFF,
+// and writes them to "<xliffFolder>/<lang>/<domain>.xlf"
+func ExportXLIFFFiles(outputFolder string, xliffFolder string, languages []string, version string, sourceLang string) error {
+	catalogs, err := readCatalogs(outputFolder, languages)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range catalogs {
+		doc, err := ExportXLIFF(c.File, version, sourceLang, c.Language, c.Domain)
+		if err != nil {
+			return err
+		}
+
+		b, err := xml.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		langFolder := filepath.Join(xliffFolder, c.Language)
+		err = os.MkdirAll(langFolder, 0755)
+		if err != nil {
+			return fmt.Errorf("could not create folder '%s': %w", langFolder, err)
+		}
+
+		xliffPath := filepath.Join(langFolder, c.Domain+".xlf")
+		err = os.WriteFile(xliffPath, append([]byte(xml.Header), append(b, '\n')...), 0644)
+		if err != nil {
+			return fmt.Errorf("could not write file '%s': %w", xliffPath, err)
+		}
+	}
+	return nil
+}
+
+// ImportXLIFFFiles applies the translations in "<xliffFolder>/<lang>/<domain>.xlf" to the
+// matching PO files in outputFolder, and returns all conflicts found
+func ImportXLIFFFiles(outputFolder string, xliffFolder string, languages []string, overwrite bool) ([]string, error) {
+	var conflicts []string
+	for _, lang := range languages {
+		paths, err := filepath.Glob(filepath.Join(xliffFolder, lang, "*.xlf"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, xliffPath := range paths {
+			domain := strings.TrimSuffix(filepath.Base(xliffPath), ".xlf")
+			poPath := filepath.Join(outputFolder, lang, domain+".po")
+
+			b, err := os.ReadFile(xliffPath)
+			if err != nil {
+				return nil, err
+			}
+
+			units, err := ParseXLIFF(b)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse '%s': %w", xliffPath, err)
+			}
+
+			f, err := ReadFile(poPath)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, c := range ImportXLIFF(f, units, overwrite) {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %s", poPath, c))
+			}
+
+			err = WriteFile(poPath, f)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,101 @@
+package po
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const xliffTestPO = `msgid ""
+msgstr ""
+"Language: sv_SE\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+# Translator comment
+#. Extracted comment
+#: main.go:10
+msgid "Hello"
+msgstr "Hej"
+
+#: main.go:11
+msgctxt "menu"
+msgid "Open"
+msgstr ""
+
+#: main.go:12
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] ""
+msgstr[1] ""
+
+#, fuzzy
+msgid "Fuzzy"
+msgstr "Luddig"
+`
+
+func TestXLIFF12References(t *testing.T) {
+	f := NewTemplate()
+	f.Entries = append(f.Entries, &Entry{ID: "Hello", References: []string{"views/a.go:10:3", "b.go:7", "c.go"}})
+
+	doc, err := ExportXLIFF(f, "1.2", "en", "sv_SE", "default")
+	require.Nil(t, err)
+
+	b, err := xml.Marshal(doc)
+	require.Nil(t, err)
+	require.Contains(t, string(b), `<context context-type="sourcefile">views/a.go</context><context context-type="linenumber">10</context>`)
+	require.Contains(t, string(b), `<context context-type="sourcefile">b.go</context><context context-type="linenumber">7</context>`)
+	require.Contains(t, string(b), `<context context-type="sourcefile">c.go</context></context-group>`)
+	require.NotContains(t, string(b), "10:3")
+}
+
+func TestXLIFFRoundTrip(t *testing.T) {
+	for _, version := range []string{"1.2", "2.0"} {
+		f, err := Parse(strings.NewReader(xliffTestPO))
+		require.Nil(t, err)
+
+		doc, err := ExportXLIFF(f, version, "en", "sv_SE", "default")
+		require.Nil(t, err)
+
+		b, err := xml.Marshal(doc)
+		require.Nil(t, err)
+		require.Contains(t, string(b), "Translator comment")
+		require.Contains(t, string(b), "Extracted comment")
+		require.Contains(t, string(b), "menu")
+
+		units, err := ParseXLIFF(b)
+		require.Nil(t, err)
+		require.Len(t, units, 5)
+
+		translations := map[string]string{
+			"Hello":    "Hallå",
+			"Open":     "Öppna",
+			"One file": "En fil",
+			"%d files": "%d filer",
+			"Fuzzy":    "Luddig",
+		}
+		for i := range units {
+			units[i].Target = translations[units[i].Source]
+			units[i].HasTarget = true
+			units[i].Fuzzy = units[i].Source == "Open"
+		}
+		units = append(units, XLIFFUnit{ID: "unknown", Source: "Removed", Target: "Borttagen", HasTarget: true})
+
+		conflicts := ImportXLIFF(f, units, false)
+		require.Len(t, conflicts, 2, "version %s", version)
+		require.Contains(t, conflicts[0], `"Hello" is already translated as "Hej", not "Hallå"`)
+		require.Contains(t, conflicts[1], `unit unknown ("Removed") has no matching entry`)
+
+		require.Equal(t, []string{"Hej"}, f.Entries[1].Str)
+		require.Equal(t, []string{"Öppna"}, f.Entries[2].Str)
+		require.True(t, f.Entries[2].HasFlag("fuzzy"))
+		require.Equal(t, []string{"En fil", "%d filer"}, f.Entries[3].Str)
+		require.False(t, f.Entries[4].HasFlag("fuzzy"))
+
+		f, err = Parse(strings.NewReader(xliffTestPO))
+		require.Nil(t, err)
+		ImportXLIFF(f, units, true)
+		require.Equal(t, []string{"Hallå"}, f.Entries[1].Str)
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/yzzyx/makemessage/extract"
+	"github.com/yzzyx/makemessage/po"
+)
+
+// watchDelay is how long to wait for more changes before updating the message files,
+// as editors often write a file in several steps
+const watchDelay = 300 * time.Millisecond
+
+// watcher updates the message files when the packages or templates they were extracted from change
+type watcher struct {
+	*fsnotify.Watcher
+
+	msgHolder *extract.MsgHolder
+	opts      extract.GoOptions
+	mapping   map[string]extract.Extractor
+	tm        *po.TranslationMemory
+	basePaths []string // Absolute paths of the package paths
+
+	// goStrings holds the strings found in go code, by the absolute folder of their package. References are
+	// relative to the package path, so strings from packages in different package paths can't be told apart
+	// by their references alone.
+	goStrings map[string][]extract.TranslationString
+}
+
+// isHidden checks if the file at path is hidden, such as ".git" or an editor swap file
+func isHidden(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".")
+}
+
+// isBelow checks if path is the same as, or inside, the folder dir
+func isBelow(path string, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// packageBase returns the absolute package path that the file or folder at path is parsed as part of
+func (w *watcher) packageBase(path string) (string, bool) {
+	for _, basePath := range w.basePaths {
+		if filepath.Dir(path) == basePath || (*recurse && isBelow(path, basePath)) {
+			return basePath, true
+		}
+	}
+	return "", false
+}
+
+// templatePath returns the absolute path as it's written in references, i.e. relative to the template
+// path it was found in, in the same way as when the template paths are first walked
+func (w *watcher) templatePath(path string) (string, bool) {
+	for _, p := range *templatePaths {
+		absPath, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+
+		if isBelow(path, absPath) {
+			rel, err := filepath.Rel(absPath, path)
+			if err != nil {
+				continue
+			}
+			return filepath.Join(p, rel), true
+		}
+	}
+	return "", false
+}
+
+// watchFolder watches the folder at path and all folders in it, except hidden ones.
+// If queue is not nil, all files found are added to it.
+func (w *watcher) watchFolder(path string, queue map[string]bool) error {
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if p != path && isHidden(p) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.IsDir() {
+			if queue != nil {
+				queue[p] = true
+			}
+			return nil
+		}
+		return w.Add(p)
+	})
+}
+
+// stringKey identifies a string found in go code, among the strings in a MsgHolder
+func stringKey(s extract.TranslationString) string {
+	return strings.Join([]string{s.Reference(true), s.Domain, s.Context, s.Singular, s.Plural}, "\x00")
+}
+
+// removePackages removes the strings found in the packages whose absolute folder matches match, and returns
+// the domains they were removed from. If the same string was found in several package paths, only one
+// occurrence is removed for each occurrence found in a matching package.
+func (w *watcher) removePackages(match func(dir string) bool) []string {
+	counts := map[string]int{}
+	for dir, strs := range w.goStrings {
+		if !match(dir) {
+			continue
+		}
+		for _, s := range strs {
+			counts[stringKey(s)]++
+		}
+		delete(w.goStrings, dir)
+	}
+
+	if len(counts) == 0 {
+		return nil
+	}
+	return w.msgHolder.Remove(func(s extract.TranslationString) bool {
+		if filepath.Ext(s.Path) != ".go" || counts[stringKey(s)] == 0 {
+			return false
+		}
+		counts[stringKey(s)]--
+		return true
+	})
+}
+
+// update re-parses the changed files and folders, given as absolute paths, and updates
+// the message files of all domains that have changed
+func (w *watcher) update(changed map[string]bool) error {
+	affected := map[string]bool{}
+	remove := func(match func(s extract.TranslationString) bool) {
+		for _, domain := range w.msgHolder.Remove(match) {
+			affected[domain] = true
+		}
+	}
+	removePackages := func(match func(dir string) bool) {
+		for _, domain := range w.removePackages(match) {
+			affected[domain] = true
+		}
+	}
+
+	changes := extract.NewMsgHolder()
+	goFolders := map[string][]string{} // Folders to parse, by package path
+	for path := range changed {
+		st, err := os.Stat(path)
+		exists := err == nil
+
+		if basePath, ok := w.packageBase(path); ok {
+			switch {
+			case filepath.Ext(path) == ".go":
+				// Parse the whole package again, as the file may use types from other files in it
+				dir := filepath.Dir(path)
+				removePackages(func(d string) bool {
+					return d == dir
+				})
+				if _, err := os.Stat(dir); err == nil && !containsString(goFolders[basePath], dir) {
+					goFolders[basePath] = append(goFolders[basePath], dir)
+				}
+			case !exists:
+				// A removed folder
+				removePackages(func(d string) bool {
+					return isBelow(d, path)
+				})
+			}
+		}
+
+		_, hasExtractor := w.mapping[filepath.Ext(path)]
+		if p, ok := w.templatePath(path); ok && (hasExtractor || filepath.Ext(path) != ".go") && (!exists || st.Mode().IsRegular()) {
+			remove(func(s extract.TranslationString) bool {
+				return isBelow(s.Path, p)
+			})
+
+			if exists {
+				err = extractTemplate(w.mapping, w.opts.Cache, w.opts.Domains, p, changes)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if w.opts.DetectWrappers && len(goFolders) > 0 {
+		// Wrappers may be used in any package, so all of them must be parsed again
+		removePackages(func(string) bool {
+			return true
+		})
+
+		goFolders = map[string][]string{}
+		for _, p := range *packagePaths {
+			basePath, folderList, err := packageFolders(p)
+			if err != nil {
+				return err
+			}
+			goFolders[basePath] = folderList
+		}
+	}
+
+	for basePath, folders := range goFolders {
+		err := parsePackages(basePath, folders, changes, w.goStrings, w.opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, domain := range changes.Domains() {
+		affected[domain] = true
+		for _, s := range changes.Strings(domain) {
+			w.msgHolder.Add(s)
+		}
+	}
+
+	var domains []string
+	for domain := range affected {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	if len(domains) == 0 {
+		return nil
+	}
+
+	err := writeMessages(w.msgHolder, domains, w.tm, true)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Updated domains:", strings.Join(domains, ", "))
+	return nil
+}
+
+// containsString checks if list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// watchFiles watches the package and template paths, and updates the message files
+// with the strings in the files that change, until the program is stopped
+func watchFiles(msgHolder *extract.MsgHolder, goStrings map[string][]extract.TranslationString, opts extract.GoOptions,
+	mapping map[string]extract.Extractor, tm *po.TranslationMemory) error {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fsWatcher.Close()
+
+	// Timings are only shown for the first run
+	opts.Timings = nil
+
+	w := &watcher{
+		Watcher:   fsWatcher,
+		msgHolder: msgHolder,
+		opts:      opts,
+		mapping:   mapping,
+		tm:        tm,
+		goStrings: goStrings,
+	}
+
+	for _, p := range *packagePaths {
+		basePath, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		w.basePaths = append(w.basePaths, basePath)
+
+		if *recurse {
+			err = w.watchFolder(basePath, nil)
+		} else {
+			err = w.Add(basePath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, p := range *templatePaths {
+		absPath, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+
+		err = w.watchFolder(absPath, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Watching for changes...")
+
+	var timer <-chan time.Time
+	changed := map[string]bool{}
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod || isHidden(event.Name) {
+				continue
+			}
+
+			path, err := filepath.Abs(event.Name)
+			if err != nil {
+				return err
+			}
+			changed[path] = true
+
+			// Watch new folders, and parse the files that were created in them before they were watched
+			if st, err := os.Stat(path); err == nil && st.IsDir() && event.Has(fsnotify.Create) {
+				if _, ok := w.packageBase(path); !ok || *recurse {
+					err = w.watchFolder(path, changed)
+					if err != nil {
+						fmt.Println("Cannot watch folder:", err)
+					}
+				}
+			}
+			timer = time.After(watchDelay)
+
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Println("Error watching files:", err)
+
+		case <-timer:
+			timer = nil
+			err = w.update(changed)
+			if err != nil {
+				fmt.Println("Cannot update messages:", err)
+			}
+			changed = map[string]bool{}
+		}
+	}
+}
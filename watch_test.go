@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yzzyx/makemessage/extract"
+	"github.com/yzzyx/makemessage/po"
+)
+
+// writeFiles writes the files, given by their path relative to dir, creating folders as needed
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+// newTestWatcher extracts the strings from a module with two packages and a template folder in dir,
+// writes the message files, and returns a watcher for them
+func newTestWatcher(t *testing.T, dir string) *watcher {
+	writeFiles(t, dir, map[string]string{
+		"go.mod":           "module example.com/app\n\ngo 1.21\n",
+		"i18n/i18n.go":     "package i18n\n\nfunc T(s string) string { return s }\n",
+		"a/a.go":           "package a\n\nimport \"example.com/app/i18n\"\n\nvar _ = i18n.T(\"From a\")\n",
+		"b/b.go":           "package b\n\nimport \"example.com/app/i18n\"\n\nvar _ = i18n.T(\"From b\")\n",
+		"tpl/index.html":   "{% load i18n %}{% trans \"From template\" %}\n",
+		"tpl/ignored.json": "{}\n",
+	})
+
+	*packagePaths = []string{dir}
+	*recurse = true
+	*templatePaths = []string{filepath.Join(dir, "tpl")}
+	*outputPath = filepath.Join(dir, "locales")
+	*languages = []string{"sv"}
+
+	keyword, err := extract.ParseKeyword("T")
+	require.Nil(t, err)
+	opts := extract.GoOptions{Keywords: []extract.Keyword{keyword}}
+	mapping, err := extractorMapping(opts.Keywords)
+	require.Nil(t, err)
+
+	msgHolder := extract.NewMsgHolder()
+	goStrings := map[string][]extract.TranslationString{}
+	basePath, folders, err := packageFolders(dir)
+	require.Nil(t, err)
+	require.Nil(t, parsePackages(basePath, folders, msgHolder, goStrings, opts))
+	require.Nil(t, extractTemplates(mapping, nil, opts.Domains, msgHolder))
+	require.Nil(t, writeMessages(msgHolder, msgHolder.Domains(), nil, false))
+
+	return &watcher{
+		msgHolder: msgHolder,
+		opts:      opts,
+		mapping:   mapping,
+		basePaths: []string{basePath},
+		goStrings: goStrings,
+	}
+}
+
+// messageIDs returns the msgids of the strings in msgHolder, and of the entries that aren't obsolete
+// in the message file, in sorted order
+func messageIDs(t *testing.T, msgHolder *extract.MsgHolder) ([]string, []string) {
+	var found []string
+	for _, s := range msgHolder.Strings("default") {
+		found = append(found, s.Singular)
+	}
+	sort.Strings(found)
+
+	f, err := po.ReadFile(filepath.Join(*outputPath, "sv", "default.po"))
+	require.Nil(t, err)
+	var written []string
+	for _, e := range f.Entries {
+		if !e.Obsolete && e.ID != "" {
+			written = append(written, e.ID)
+		}
+	}
+	sort.Strings(written)
+	return found, written
+}
+
+func TestWatcherUpdate(t *testing.T) {
+	defer func(p, tp []string, r bool, o string, l []string) {
+		*packagePaths, *templatePaths, *recurse, *outputPath, *languages = p, tp, r, o, l
+	}(*packagePaths, *templatePaths, *recurse, *outputPath, *languages)
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T, dir string) []string // Changes the files, and returns the paths changed
+		want   []string
+	}{
+		{
+			name: "changed go file",
+			change: func(t *testing.T, dir string) []string {
+				writeFiles(t, dir, map[string]string{
+					"a/a.go": "package a\n\nimport \"example.com/app/i18n\"\n\nvar _ = i18n.T(\"Changed in a\")\n",
+				})
+				return []string{"a/a.go"}
+			},
+			want: []string{"Changed in a", "From b", "From template"},
+		},
+		{
+			name: "changed template",
+			change: func(t *testing.T, dir string) []string {
+				writeFiles(t, dir, map[string]string{
+					"tpl/index.html": "{% load i18n %}{% trans \"Changed template\" %}\n",
+				})
+				return []string{"tpl/index.html", "tpl/ignored.json"}
+			},
+			want: []string{"Changed template", "From a", "From b"},
+		},
+		{
+			name: "deleted package folder",
+			change: func(t *testing.T, dir string) []string {
+				require.Nil(t, os.RemoveAll(filepath.Join(dir, "b")))
+				return []string{"b"}
+			},
+			want: []string{"From a", "From template"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := filepath.EvalSymlinks(t.TempDir())
+			require.Nil(t, err)
+			w := newTestWatcher(t, dir)
+
+			found, written := messageIDs(t, w.msgHolder)
+			require.Equal(t, []string{"From a", "From b", "From template"}, found)
+			require.Equal(t, found, written)
+
+			changed := map[string]bool{}
+			for _, p := range tt.change(t, dir) {
+				changed[filepath.Join(dir, p)] = true
+			}
+			require.Nil(t, w.update(changed))
+
+			found, written = messageIDs(t, w.msgHolder)
+			require.Equal(t, tt.want, found)
+			require.Equal(t, tt.want, written)
+		})
+	}
+}